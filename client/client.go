@@ -20,6 +20,9 @@ type Config struct {
 	LoadBalancingPolicy string `yaml:"load_balancing_policy" mapstructure:"load_balancing_policy"`
 	ServerName          string `yaml:"server_name" mapstructure:"server_name"`
 	Retries             uint   `yaml:"retries" mapstructure:"retries"`
+	// DecodeErrors returns the errors of calls as *e.Status, see ErrorUnaryClientInterceptor.
+	// Their Error() is the bare message instead of "rpc error: code = ... desc = ...".
+	DecodeErrors bool `yaml:"decode_errors" mapstructure:"decode_errors"`
 }
 
 func DefaultConfig() *Config {
@@ -46,12 +49,17 @@ func NewConnection(cfg Config, opts ...grpc.DialOption) (cc grpc.ClientConnInter
 		dialOpts = append(dialOpts, grpc.WithInsecure())
 	}
 
+	unaryInterceptors := []grpc.UnaryClientInterceptor{
+		otelgrpc.UnaryClientInterceptor(),
+		grpc_prometheus.UnaryClientInterceptor,
+	}
+	if cfg.DecodeErrors {
+		unaryInterceptors = append(unaryInterceptors, ErrorUnaryClientInterceptor())
+		dialOpts = append(dialOpts, grpc.WithChainStreamInterceptor(ErrorStreamClientInterceptor()))
+	}
 	dialOpts = append(dialOpts,
 		grpc.WithDefaultServiceConfig(rrLoadBalancing),
-		grpc.WithChainUnaryInterceptor(
-			otelgrpc.UnaryClientInterceptor(),
-			grpc_prometheus.UnaryClientInterceptor,
-		),
+		grpc.WithChainUnaryInterceptor(unaryInterceptors...),
 	)
 
 	if cfg.Retries > 0 {
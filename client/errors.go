@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+
+	"github.com/tikivn/tikit-go-kit/e"
+	"google.golang.org/grpc"
+)
+
+// ErrorUnaryClientInterceptor turns the errors of calls into *e.Status, see e.FromError,
+// so that callers read their HTTP status, reason and details or match them against catalog errors.
+// The Error() of a decoded error is its message only, callers matching the "rpc error: " text
+// of status errors have to switch to status.Code or errors.Is first.
+func ErrorUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return decodeError(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// ErrorStreamClientInterceptor is the stream counterpart of ErrorUnaryClientInterceptor.
+func ErrorStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, decodeError(err)
+		}
+		return &errorClientStream{ClientStream: cs}, nil
+	}
+}
+
+type errorClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *errorClientStream) SendMsg(m interface{}) error {
+	return decodeError(s.ClientStream.SendMsg(m))
+}
+
+func (s *errorClientStream) RecvMsg(m interface{}) error {
+	return decodeError(s.ClientStream.RecvMsg(m))
+}
+
+func (s *errorClientStream) CloseSend() error {
+	return decodeError(s.ClientStream.CloseSend())
+}
+
+// decodeError keeps io.EOF and other errors without a gRPC status as they are.
+func decodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if s, ok := e.FromError(err); ok {
+		return s
+	}
+	return err
+}
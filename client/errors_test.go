@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tikivn/tikit-go-kit/e"
+	"github.com/tikivn/tikit-go-kit/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var errOrderLocked = e.NewCatalog("client.test").Register(e.Def{
+	Reason:  "ORDER_LOCKED",
+	Code:    codes.FailedPrecondition,
+	Message: "order {order_id} is locked",
+	Params:  []string{"order_id"},
+})
+
+type lockedHealthServer struct {
+	pb.UnimplementedHealthServiceServer
+}
+
+func (lockedHealthServer) Liveness(context.Context, *pb.LivenessRequest) (*pb.LivenessResponse, error) {
+	return nil, errOrderLocked.New(e.Int("order_id", 42)).SetHttpStatus(http.StatusLocked)
+}
+
+func TestNewConnection_errors(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	pb.RegisterHealthServiceServer(s, lockedHealthServer{})
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	call := func(cfg Config) error {
+		cc, err := NewConnection(cfg, grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}))
+		require.NoError(t, err)
+		_, err = pb.NewHealthServiceClient(cc).Liveness(context.Background(), &pb.LivenessRequest{})
+		return err
+	}
+
+	err := call(Config{Address: "bufnet", DecodeErrors: true})
+	var got *e.Status
+	require.True(t, errors.As(err, &got))
+	assert.Equal(t, http.StatusLocked, got.HTTPStatus)
+	assert.Equal(t, "order 42 is locked", got.Message())
+	assert.True(t, errors.Is(err, errOrderLocked))
+
+	// errors are left as gRPC status errors by default
+	err = call(Config{Address: "bufnet"})
+	assert.False(t, errors.As(err, &got))
+	assert.Equal(t, "rpc error: code = FailedPrecondition desc = order 42 is locked", err.Error())
+}
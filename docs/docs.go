@@ -0,0 +1,9 @@
+// Package docs embeds the OpenAPI documents generated for the kit's protos.
+package docs
+
+import "embed"
+
+// FS holds the *.swagger.json documents.
+//
+//go:embed *.swagger.json
+var FS embed.FS
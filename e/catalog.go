@@ -0,0 +1,262 @@
+package e
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Def declares a business error of a catalog.
+type Def struct {
+	// Reason is the stable UPPER_SNAKE_CASE code clients match on, e.g. "ORDER_NOT_FOUND".
+	Reason string
+	// Code is the gRPC code of the error.
+	Code codes.Code
+	// HTTPStatus of the error, DefaultHTTPStatus of the code when zero.
+	HTTPStatus int
+	// Message is the default message, "{name}" placeholders are replaced by the params of an instance.
+	Message string
+	// Params are the names of the params every instance is created with, including those of the placeholders.
+	Params []string
+	// Retryable tells whether the call may succeed when retried as is.
+	Retryable bool
+	// Description documents when the error happens, for the listing only.
+	Description string
+}
+
+// Entry is an error registered in a catalog. It matches its instances with errors.Is.
+type Entry struct {
+	Def
+	Domain string
+}
+
+// Catalog holds the business errors of a domain, usually the service name, e.g. "order.tiki.vn".
+type Catalog struct {
+	domain string
+
+	mu      sync.RWMutex
+	entries []*Entry
+	reasons map[string]*Entry
+}
+
+var (
+	catalogsMu sync.RWMutex
+	catalogs   = map[string]*Catalog{}
+)
+
+// NewCatalog returns the catalog of the domain, created on the first call.
+func NewCatalog(domain string) *Catalog {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+
+	if c, ok := catalogs[domain]; ok {
+		return c
+	}
+	c := &Catalog{domain: domain, reasons: map[string]*Entry{}}
+	catalogs[domain] = c
+	return c
+}
+
+// Domain returns the domain of the catalog.
+func (c *Catalog) Domain() string {
+	return c.domain
+}
+
+var messagePlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// Register adds an error to the catalog, it panics when the reason is empty or taken, or when a placeholder
+// of the message is not declared in Params, so it is meant to be called from package level var declarations.
+func (c *Catalog) Register(def Def) *Entry {
+	if def.Reason == "" {
+		panic("e: register error without reason")
+	}
+	for _, m := range messagePlaceholder.FindAllStringSubmatch(def.Message, -1) {
+		if !containsParam(def.Params, m[1]) {
+			panic(fmt.Sprintf("e: error %s has undeclared param %s in its message", def.Reason, m[1]))
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.reasons[def.Reason]; ok {
+		panic(fmt.Sprintf("e: error %s registered twice in %s", def.Reason, c.domain))
+	}
+	entry := &Entry{Def: def, Domain: c.domain}
+	c.entries = append(c.entries, entry)
+	c.reasons[def.Reason] = entry
+	return entry
+}
+
+// Entries returns the registered errors sorted by reason.
+func (c *Catalog) Entries() []*Entry {
+	c.mu.RLock()
+	entries := append([]*Entry(nil), c.entries...)
+	c.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Reason < entries[j].Reason })
+	return entries
+}
+
+// Lookup returns the registered error of the reason.
+func (c *Catalog) Lookup(reason string) (*Entry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.reasons[reason]
+	return entry, ok
+}
+
+// Markdown returns a table of the registered errors for documentation.
+func (c *Catalog) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", c.domain)
+	b.WriteString("| Reason | gRPC code | HTTP status | Retryable | Message | Description |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, entry := range c.Entries() {
+		fmt.Fprintf(&b, "| `%s` | %s | %d | %t | %s | %s |\n",
+			entry.Reason, entry.Code, entry.httpStatus(), entry.Retryable,
+			markdownCell(entry.Message), markdownCell(entry.Description))
+	}
+	return b.String()
+}
+
+func containsParam(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// LookupError returns the catalog entry err is an instance of, by the reason and domain of its ErrorInfo.
+func LookupError(err error) (*Entry, bool) {
+	var s *Status
+	if !errors.As(err, &s) {
+		return nil, false
+	}
+	info, ok := s.ErrorInfo()
+	if !ok {
+		return nil, false
+	}
+
+	catalogsMu.RLock()
+	c, ok := catalogs[info.Domain]
+	catalogsMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return c.Lookup(info.Reason)
+}
+
+// IsRetryable reports whether err is an instance of a retryable catalog error or carries a RetryInfo detail.
+func IsRetryable(err error) bool {
+	if entry, ok := LookupError(err); ok && entry.Retryable {
+		return true
+	}
+	var s *Status
+	if errors.As(err, &s) {
+		_, ok := s.RetryAfter()
+		return ok
+	}
+	return false
+}
+
+// Param is a typed parameter of a catalog error instance.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// String is a string parameter.
+func String(key, value string) Param {
+	return Param{Key: key, Value: value}
+}
+
+// Int is an int parameter.
+func Int(key string, value int) Param {
+	return Param{Key: key, Value: strconv.Itoa(value)}
+}
+
+// Int64 is an int64 parameter.
+func Int64(key string, value int64) Param {
+	return Param{Key: key, Value: strconv.FormatInt(value, 10)}
+}
+
+// Float64 is a float64 parameter.
+func Float64(key string, value float64) Param {
+	return Param{Key: key, Value: strconv.FormatFloat(value, 'f', -1, 64)}
+}
+
+// Bool is a bool parameter.
+func Bool(key string, value bool) Param {
+	return Param{Key: key, Value: strconv.FormatBool(value)}
+}
+
+// Duration is a time.Duration parameter.
+func Duration(key string, value time.Duration) Param {
+	return Param{Key: key, Value: value.String()}
+}
+
+// New returns an instance of the error with the params filled in the message
+// and set as the metadata of its ErrorInfo detail. Like a bad format string, a missing
+// or undeclared param is a programming error and New panics.
+func (entry *Entry) New(params ...Param) *Status {
+	message := entry.Message
+	var metadata map[string]string
+	if len(params) > 0 {
+		metadata = make(map[string]string, len(params))
+		replacements := make([]string, 0, 2*len(params))
+		for _, p := range params {
+			if !containsParam(entry.Params, p.Key) {
+				panic(fmt.Sprintf("e: error %s has no param %s", entry.Reason, p.Key))
+			}
+			metadata[p.Key] = p.Value
+			replacements = append(replacements, "{"+p.Key+"}", p.Value)
+		}
+		message = strings.NewReplacer(replacements...).Replace(message)
+	}
+	for _, name := range entry.Params {
+		if _, ok := metadata[name]; !ok {
+			panic(fmt.Sprintf("e: error %s created without param %s", entry.Reason, name))
+		}
+	}
+
+	s := Error(entry.Code, message)
+	s.HTTPStatus = entry.httpStatus()
+	return s.WithReason(entry.Reason, entry.Domain, metadata)
+}
+
+// Error returns the reason, so that an entry can be the target of errors.Is.
+func (entry *Entry) Error() string {
+	return entry.Reason
+}
+
+func (entry *Entry) httpStatus() int {
+	if entry.HTTPStatus != 0 {
+		return entry.HTTPStatus
+	}
+	return DefaultHTTPStatus(entry.Code)
+}
+
+// Is reports whether the status is an instance of a catalog entry, matched by reason and domain,
+// or its cause matches target.
+func (s *Status) Is(target error) bool {
+	entry, ok := target.(*Entry)
+	if !ok {
+		return s.isCause(target)
+	}
+	info, ok := s.ErrorInfo()
+	return ok && info.Reason == entry.Reason && info.Domain == entry.Domain
+}
@@ -0,0 +1,87 @@
+package e
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	testCatalog = NewCatalog("catalog.test")
+
+	errOrderNotFound = testCatalog.Register(Def{
+		Reason:      "ORDER_NOT_FOUND",
+		Code:        codes.NotFound,
+		HTTPStatus:  http.StatusNotFound,
+		Message:     "order {order_id} not found",
+		Params:      []string{"order_id"},
+		Description: "The order does not exist or belongs to another customer.",
+	})
+	errStockReserved = testCatalog.Register(Def{
+		Reason:    "STOCK_RESERVED",
+		Code:      codes.Aborted,
+		Message:   "{quantity} items of {sku} are reserved | retry later",
+		Params:    []string{"sku", "quantity"},
+		Retryable: true,
+	})
+)
+
+func TestEntry_New(t *testing.T) {
+	s := errOrderNotFound.New(Int64("order_id", 42))
+
+	assert.Equal(t, codes.NotFound, s.Code())
+	assert.Equal(t, "order 42 not found", s.Message())
+	assert.Equal(t, http.StatusNotFound, s.HTTPStatus)
+	info, ok := s.ErrorInfo()
+	require.True(t, ok)
+	assert.Equal(t, "catalog.test", info.Domain)
+	assert.Equal(t, map[string]string{"order_id": "42"}, info.Metadata)
+
+	assert.True(t, errors.Is(s, errOrderNotFound))
+	assert.True(t, errors.Is(fmt.Errorf("get order: %w", s), errOrderNotFound))
+	assert.False(t, errors.Is(s, errStockReserved))
+	assert.False(t, IsRetryable(s))
+
+	s = errStockReserved.New(String("sku", "A-1"), Int("quantity", 2))
+	assert.Equal(t, "2 items of A-1 are reserved | retry later", s.Message())
+	assert.Equal(t, http.StatusConflict, s.HTTPStatus)
+	assert.True(t, IsRetryable(s))
+	assert.True(t, IsRetryable(fmt.Errorf("reserve stock: %w", s)))
+	entry, ok := LookupError(fmt.Errorf("reserve stock: %w", s))
+	require.True(t, ok)
+	assert.Same(t, errStockReserved, entry)
+
+	assert.Panics(t, func() { errOrderNotFound.New() }, "missing param")
+	assert.Panics(t, func() { errOrderNotFound.New(Int64("order_id", 42), String("sku", "A-1")) }, "undeclared param")
+}
+
+func TestEntry_Is_remote(t *testing.T) {
+	// instances received over gRPC still match their entry
+	remote := &Status{Err: status.Convert(errOrderNotFound.New(Int64("order_id", 42)).GRPCStatus().Err())}
+	assert.True(t, errors.Is(remote, errOrderNotFound))
+	entry, ok := LookupError(remote)
+	require.True(t, ok)
+	assert.Same(t, errOrderNotFound, entry)
+}
+
+func TestCatalog_Register(t *testing.T) {
+	assert.Same(t, testCatalog, NewCatalog("catalog.test"))
+	assert.Panics(t, func() { testCatalog.Register(Def{Reason: "ORDER_NOT_FOUND"}) })
+	assert.Panics(t, func() { testCatalog.Register(Def{}) })
+	assert.Panics(t, func() { testCatalog.Register(Def{Reason: "ORDER_LOCKED", Message: "order {order_id} is locked"}) })
+}
+
+func TestCatalog_Markdown(t *testing.T) {
+	assert.Equal(t, "## catalog.test\n\n"+
+		"| Reason | gRPC code | HTTP status | Retryable | Message | Description |\n"+
+		"|---|---|---|---|---|---|\n"+
+		"| `ORDER_NOT_FOUND` | NotFound | 404 | false | order {order_id} not found | The order does not exist or belongs to another customer. |\n"+
+		"| `STOCK_RESERVED` | Aborted | 409 | true | {quantity} items of {sku} are reserved \\| retry later |  |\n",
+		testCatalog.Markdown())
+}
@@ -0,0 +1,65 @@
+package e
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+)
+
+const maxStackDepth = 32
+
+// WithCause keeps err as the internal cause of the status and captures the stack of the caller.
+// The cause is matched by errors.Is and errors.As and logged by the server, it is never sent to clients.
+func (s *Status) WithCause(err error) *Status {
+	s.cause = err
+	if s.stack == nil {
+		s.stack = callers(3)
+	}
+	return s
+}
+
+// Cause returns the internal cause of the status.
+func (s Status) Cause() error {
+	return s.cause
+}
+
+// StackTrace returns the stack captured with the cause, one "function file:line" per frame.
+func (s Status) StackTrace() []string {
+	if len(s.stack) == 0 {
+		return nil
+	}
+
+	var trace []string
+	frames := runtime.CallersFrames(s.stack)
+	for {
+		f, more := frames.Next()
+		trace = append(trace, f.Function+" "+f.File+":"+strconv.Itoa(f.Line))
+		if !more {
+			break
+		}
+	}
+	return trace
+}
+
+// CauseChain returns the messages of the cause and the errors it wraps, outermost first.
+func (s Status) CauseChain() []string {
+	var chain []string
+	for err := s.cause; err != nil; err = errors.Unwrap(err) {
+		chain = append(chain, err.Error())
+	}
+	return chain
+}
+
+// As finds the first error in the cause chain that matches target.
+func (s *Status) As(target interface{}) bool {
+	return s.cause != nil && errors.As(s.cause, target)
+}
+
+func (s *Status) isCause(target error) bool {
+	return s.cause != nil && errors.Is(s.cause, target)
+}
+
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	return pcs[:runtime.Callers(skip, pcs)]
+}
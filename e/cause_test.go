@@ -0,0 +1,61 @@
+package e
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWrapError_cause(t *testing.T) {
+	pathErr := &fs.PathError{Op: "open", Path: "orders.csv", Err: fs.ErrNotExist}
+	err := fmt.Errorf("import orders: %w", pathErr)
+
+	s := WrapError(err)
+	assert.Equal(t, codes.InvalidArgument, s.Code())
+	assert.Equal(t, clientErrMsg, s.Error())
+	assert.Same(t, err, s.Cause())
+
+	assert.True(t, errors.Is(s, fs.ErrNotExist))
+	var target *fs.PathError
+	require.True(t, errors.As(s, &target))
+	assert.Equal(t, "orders.csv", target.Path)
+
+	assert.Equal(t, []string{
+		"import orders: open orders.csv: file does not exist",
+		"open orders.csv: file does not exist",
+		"file does not exist",
+	}, s.CauseChain())
+
+	trace := s.StackTrace()
+	require.NotEmpty(t, trace)
+	assert.True(t, strings.HasPrefix(trace[0], "github.com/tikivn/tikit-go-kit/e.WrapError "), trace[0])
+	assert.Contains(t, trace[1], "TestWrapError_cause")
+}
+
+func TestWrapError_status(t *testing.T) {
+	s := Error(codes.NotFound, "order not found").WithCause(context.Canceled)
+	assert.Same(t, s, WrapError(fmt.Errorf("get order: %w", s)))
+	assert.Same(t, s, WrapErrorf(s, "ignored"))
+
+	wrapped := WrapErrorf(context.DeadlineExceeded, "query %s timed out", "orders")
+	assert.Equal(t, "query orders timed out", wrapped.Message())
+	assert.True(t, errors.Is(wrapped, context.DeadlineExceeded))
+	assert.False(t, errors.Is(wrapped, context.Canceled))
+}
+
+func TestStatus_causeNotSent(t *testing.T) {
+	s := Error(codes.Internal, "failed to save order").WithCause(errors.New("pq: password authentication failed"))
+
+	st := status.Convert(s)
+	assert.Equal(t, "failed to save order", st.Message())
+	assert.NotContains(t, fmt.Sprint(st.Proto()), "password")
+	assert.Equal(t, "failed to save order", s.Error())
+}
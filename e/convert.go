@@ -0,0 +1,55 @@
+package e
+
+import (
+	"errors"
+
+	"github.com/tikivn/tikit-go-kit/pb"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// FromError returns the Status of an error received from a kit server, with the HTTP status
+// it was created with and its details, so that it can be returned as is or matched with errors.Is
+// against catalog entries. Errors without a gRPC status give an Unknown Status and false.
+func FromError(err error) (*Status, bool) {
+	if err == nil {
+		return nil, true
+	}
+	var s *Status
+	if errors.As(err, &s) {
+		return s, true
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return &Status{HTTPStatus: HTTPStatusFromCode(codes.Unknown), Err: st}, false
+	}
+	sp := st.Proto()
+	httpStatus := HTTPStatusFromCode(st.Code())
+	for _, d := range sp.GetDetails() {
+		var hs pb.HTTPStatus
+		if d.MessageIs(&hs) && d.UnmarshalTo(&hs) == nil && hs.Code > 0 {
+			httpStatus = int(hs.Code)
+		}
+	}
+	return &Status{HTTPStatus: httpStatus, Err: status.FromProto(withoutHTTPStatus(sp))}, true
+}
+
+// Convert is like FromError, without telling whether err had a gRPC status.
+func Convert(err error) *Status {
+	s, _ := FromError(err)
+	return s
+}
+
+// withoutHTTPStatus returns a copy of sp without pb.HTTPStatus details.
+func withoutHTTPStatus(sp *spb.Status) *spb.Status {
+	details := make([]*anypb.Any, 0, len(sp.GetDetails()))
+	for _, d := range sp.GetDetails() {
+		if !d.MessageIs(&pb.HTTPStatus{}) {
+			details = append(details, d)
+		}
+	}
+	return &spb.Status{Code: sp.GetCode(), Message: sp.GetMessage(), Details: details}
+}
@@ -0,0 +1,48 @@
+package e
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFromError(t *testing.T) {
+	sent := errOrderNotFound.New(Int("order_id", 42)).
+		WithFieldViolation("order_id", "unknown order").
+		SetHttpStatus(http.StatusGone)
+	// what a gRPC client receives
+	received := status.Convert(sent).Err()
+
+	s, ok := FromError(received)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusGone, s.HTTPStatus)
+	assert.Equal(t, "order 42 not found", s.Message())
+	assert.Equal(t, "ORDER_NOT_FOUND", s.Reason())
+	assert.Len(t, s.FieldViolations(), 1)
+	assert.Len(t, s.Err.Details(), 2, "the HTTP status detail is read")
+	assert.True(t, errors.Is(s, errOrderNotFound))
+
+	// propagated unchanged, the HTTP status detail is not duplicated
+	again, _ := FromError(status.Convert(s).Err())
+	assert.Equal(t, http.StatusGone, again.HTTPStatus)
+	assert.Len(t, again.Err.Details(), 2)
+}
+
+func TestFromError_defaults(t *testing.T) {
+	s, ok := FromError(status.Error(codes.PermissionDenied, "not your order"))
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusForbidden, s.HTTPStatus)
+	assert.Empty(t, status.Convert(s).Details(), "the default HTTP status is not attached")
+
+	s, ok = FromError(errors.New("boom"))
+	assert.False(t, ok)
+	assert.Equal(t, codes.Unknown, s.Code())
+
+	local := Error(codes.NotFound, "order not found")
+	assert.Same(t, local, Convert(local))
+}
@@ -0,0 +1,131 @@
+package e
+
+import (
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// WithFieldViolation adds a violation to the google.rpc.BadRequest detail of the status.
+func (s *Status) WithFieldViolation(field, description string) *Status {
+	br := &errdetails.BadRequest{}
+	s.findDetail(br)
+	br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: description,
+	})
+	return s.setDetail(br)
+}
+
+// WithReason sets the google.rpc.ErrorInfo detail, reason is a stable UPPER_SNAKE_CASE code
+// and domain the service it comes from, e.g. "order.tiki.vn".
+func (s *Status) WithReason(reason, domain string, metadata map[string]string) *Status {
+	return s.setDetail(&errdetails.ErrorInfo{Reason: reason, Domain: domain, Metadata: metadata})
+}
+
+// WithRetryAfter sets the google.rpc.RetryInfo detail, telling clients when to retry.
+func (s *Status) WithRetryAfter(d time.Duration) *Status {
+	return s.setDetail(&errdetails.RetryInfo{RetryDelay: durationpb.New(d)})
+}
+
+// WithLocalizedMessage sets the google.rpc.LocalizedMessage detail, a message safe to show end users,
+// locale follows BCP 47, e.g. "vi-VN".
+func (s *Status) WithLocalizedMessage(locale, message string) *Status {
+	return s.setDetail(&errdetails.LocalizedMessage{Locale: locale, Message: message})
+}
+
+// WithDebugInfo sets the google.rpc.DebugInfo detail. It is sent to clients as is,
+// keep it out of errors returned to the public.
+func (s *Status) WithDebugInfo(detail string, stackEntries ...string) *Status {
+	return s.setDetail(&errdetails.DebugInfo{Detail: detail, StackEntries: stackEntries})
+}
+
+// WithHelpLink adds a link to the google.rpc.Help detail of the status.
+func (s *Status) WithHelpLink(description, url string) *Status {
+	help := &errdetails.Help{}
+	s.findDetail(help)
+	help.Links = append(help.Links, &errdetails.Help_Link{Description: description, Url: url})
+	return s.setDetail(help)
+}
+
+// FieldViolations returns the violations of the google.rpc.BadRequest detail.
+func (s Status) FieldViolations() []*errdetails.BadRequest_FieldViolation {
+	br := &errdetails.BadRequest{}
+	s.findDetail(br)
+	return br.GetFieldViolations()
+}
+
+// ErrorInfo returns the google.rpc.ErrorInfo detail.
+func (s Status) ErrorInfo() (*errdetails.ErrorInfo, bool) {
+	info := &errdetails.ErrorInfo{}
+	return info, s.findDetail(info)
+}
+
+// Reason returns the reason of the google.rpc.ErrorInfo detail.
+func (s Status) Reason() string {
+	info, _ := s.ErrorInfo()
+	return info.GetReason()
+}
+
+// RetryAfter returns the delay of the google.rpc.RetryInfo detail.
+func (s Status) RetryAfter() (time.Duration, bool) {
+	info := &errdetails.RetryInfo{}
+	if !s.findDetail(info) || info.RetryDelay == nil {
+		return 0, false
+	}
+	return info.RetryDelay.AsDuration(), true
+}
+
+// LocalizedMessage returns the google.rpc.LocalizedMessage detail.
+func (s Status) LocalizedMessage() (*errdetails.LocalizedMessage, bool) {
+	msg := &errdetails.LocalizedMessage{}
+	return msg, s.findDetail(msg)
+}
+
+// DebugInfo returns the google.rpc.DebugInfo detail.
+func (s Status) DebugInfo() (*errdetails.DebugInfo, bool) {
+	info := &errdetails.DebugInfo{}
+	return info, s.findDetail(info)
+}
+
+// HelpLinks returns the links of the google.rpc.Help detail.
+func (s Status) HelpLinks() []*errdetails.Help_Link {
+	help := &errdetails.Help{}
+	s.findDetail(help)
+	return help.GetLinks()
+}
+
+// findDetail unmarshals the detail of the type of m into it.
+func (s Status) findDetail(m proto.Message) bool {
+	for _, d := range s.Err.Proto().GetDetails() {
+		if d.MessageIs(m) {
+			return d.UnmarshalTo(m) == nil
+		}
+	}
+	return false
+}
+
+// setDetail replaces the detail of the type of m, or adds it. Details cannot be attached to an OK status.
+func (s *Status) setDetail(m proto.Message) *Status {
+	detail, err := anypb.New(m)
+	if err != nil || s.Err.Code() == codes.OK {
+		return s
+	}
+
+	sp := s.Err.Proto()
+	for i, d := range sp.Details {
+		if d.MessageIs(m) {
+			sp.Details[i] = detail
+			s.Err = status.FromProto(sp)
+			return s
+		}
+	}
+	sp.Details = append(sp.Details, detail)
+	s.Err = status.FromProto(sp)
+	return s
+}
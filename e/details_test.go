@@ -0,0 +1,62 @@
+package e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatus_details(t *testing.T) {
+	s := Error(codes.InvalidArgument, "invalid order").
+		WithFieldViolation("items[0].quantity", "must be positive").
+		WithFieldViolation("customer.phone", "invalid phone number").
+		WithReason("ORDER_INVALID", "order.tiki.vn", map[string]string{"order_id": "42"}).
+		WithRetryAfter(3*time.Second).
+		WithLocalizedMessage("vi-VN", "Đơn hàng không hợp lệ").
+		WithDebugInfo("validate order", "order.go:42").
+		WithHelpLink("Order API", "https://developers.tiki.vn/orders")
+
+	// read back from the status received by a client
+	got := Status{Err: status.Convert(s.GRPCStatus().Err())}
+
+	violations := got.FieldViolations()
+	require.Len(t, violations, 2)
+	assert.Equal(t, "items[0].quantity", violations[0].Field)
+	assert.Equal(t, "customer.phone", violations[1].Field)
+
+	info, ok := got.ErrorInfo()
+	require.True(t, ok)
+	assert.Equal(t, "order.tiki.vn", info.Domain)
+	assert.Equal(t, "42", info.Metadata["order_id"])
+	assert.Equal(t, "ORDER_INVALID", got.Reason())
+
+	d, ok := got.RetryAfter()
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, d)
+
+	msg, ok := got.LocalizedMessage()
+	assert.True(t, ok)
+	assert.Equal(t, "vi-VN", msg.Locale)
+
+	debug, ok := got.DebugInfo()
+	assert.True(t, ok)
+	assert.Equal(t, []string{"order.go:42"}, debug.StackEntries)
+
+	require.Len(t, got.HelpLinks(), 1)
+	assert.Equal(t, "https://developers.tiki.vn/orders", got.HelpLinks()[0].Url)
+}
+
+func TestStatus_detailsReplace(t *testing.T) {
+	s := Error(codes.Unavailable, "busy").WithRetryAfter(time.Second).WithRetryAfter(time.Minute)
+	assert.Len(t, s.Err.Details(), 1)
+	d, _ := s.RetryAfter()
+	assert.Equal(t, time.Minute, d)
+
+	ok := Error(codes.OK, "").WithReason("NONE", "", nil)
+	assert.Empty(t, ok.Err.Details())
+	assert.Equal(t, "", ok.Reason())
+}
@@ -6,8 +6,10 @@ import (
 	"strconv"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/tikivn/tikit-go-kit/pb"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 const (
@@ -18,19 +20,23 @@ const (
 type Status struct {
 	HTTPStatus int
 	Err        *status.Status
+
+	// cause and stack stay on the server, see WithCause
+	cause error
+	stack []uintptr
 }
 
-// Error new status with code and message
+// Error new status with code and message, its HTTP status is DefaultHTTPStatus of the code
 func Error(code codes.Code, message string) *Status {
 	return &Status{
-		HTTPStatus: clientErrStatus,
+		HTTPStatus: DefaultHTTPStatus(code),
 		Err:        status.New(code, message)}
 }
 
-// Errorf new status with code and message
+// Errorf new status with code and message, its HTTP status is DefaultHTTPStatus of the code
 func Errorf(code codes.Code, format string, args ...interface{}) *Status {
 	return &Status{
-		HTTPStatus: clientErrStatus,
+		HTTPStatus: DefaultHTTPStatus(code),
 		Err:        status.Newf(code, format, args...)}
 }
 
@@ -50,9 +56,21 @@ func (s *Status) ServerErr() error {
 	return s.SetHttpStatus(serverErrStatus)
 }
 
-// GRPCStatus ...
+// GRPCStatus returns the status with the HTTP status attached as a pb.HTTPStatus detail when it differs
+// from HTTPStatusFromCode, so that the gateway error handler still responds with it after the gRPC hop.
 func (s Status) GRPCStatus() *status.Status {
-	return s.Err
+	if s.HTTPStatus == 0 || s.Err.Code() == codes.OK {
+		return s.Err
+	}
+	sp := withoutHTTPStatus(s.Err.Proto())
+	if s.HTTPStatus != HTTPStatusFromCode(s.Err.Code()) {
+		detail, err := anypb.New(&pb.HTTPStatus{Code: int32(s.HTTPStatus)})
+		if err != nil {
+			return s.Err
+		}
+		sp.Details = append(sp.Details, detail)
+	}
+	return status.FromProto(sp)
 }
 
 // Code ...
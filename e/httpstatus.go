@@ -0,0 +1,93 @@
+package e
+
+import (
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+)
+
+var (
+	httpStatusMu        sync.RWMutex
+	httpStatusOverrides = map[codes.Code]int{}
+)
+
+// HTTPStatusFromCode returns the HTTP status the gateway responds with for a gRPC code.
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return http.StatusRequestTimeout
+	case codes.Unknown:
+		return http.StatusInternalServerError
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		// Note, this deliberately doesn't translate to the similarly named '412 Precondition Failed' HTTP response status.
+		return http.StatusBadRequest
+	case codes.Aborted:
+		return http.StatusConflict
+	case codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unimplemented:
+		//todo: change  http.StatusNotImplemented -> http.StatusMethodNotAllowed
+		return http.StatusMethodNotAllowed
+	case codes.Internal:
+		return http.StatusInternalServerError
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DataLoss:
+		return http.StatusInternalServerError
+	}
+
+	grpclog.Infof("Unknown gRPC error code: %v", code)
+	return http.StatusInternalServerError
+}
+
+// DefaultHTTPStatus returns the HTTP status of errors created with Error and Errorf,
+// the override of the code or HTTPStatusFromCode.
+func DefaultHTTPStatus(code codes.Code) int {
+	httpStatusMu.RLock()
+	st, ok := httpStatusOverrides[code]
+	httpStatusMu.RUnlock()
+	if ok {
+		return st
+	}
+	return HTTPStatusFromCode(code)
+}
+
+// SetDefaultHTTPStatus overrides the default HTTP status of a code, call it at start up.
+func SetDefaultHTTPStatus(code codes.Code, httpStatus int) {
+	httpStatusMu.Lock()
+	defer httpStatusMu.Unlock()
+	httpStatusOverrides[code] = httpStatus
+}
+
+// UseLegacyHTTPStatus restores 400 Bad Request as the default HTTP status of every error code,
+// for clients that depend on it.
+func UseLegacyHTTPStatus() {
+	for code := codes.Canceled; code <= codes.Unauthenticated; code++ {
+		SetDefaultHTTPStatus(code, clientErrStatus)
+	}
+}
+
+// ResetDefaultHTTPStatus removes the overrides.
+func ResetDefaultHTTPStatus() {
+	httpStatusMu.Lock()
+	defer httpStatusMu.Unlock()
+	httpStatusOverrides = map[codes.Code]int{}
+}
@@ -0,0 +1,24 @@
+package e
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestError_defaultHTTPStatus(t *testing.T) {
+	assert.Equal(t, http.StatusNotFound, Error(codes.NotFound, "order not found").HTTPStatus)
+	assert.Equal(t, http.StatusInternalServerError, Errorf(codes.Internal, "query %s", "orders").HTTPStatus)
+	assert.Equal(t, http.StatusBadRequest, Error(codes.InvalidArgument, "invalid order").HTTPStatus)
+
+	SetDefaultHTTPStatus(codes.NotFound, http.StatusOK)
+	defer ResetDefaultHTTPStatus()
+	assert.Equal(t, http.StatusOK, Error(codes.NotFound, "order not found").HTTPStatus)
+	assert.Equal(t, http.StatusNotFound, HTTPStatusFromCode(codes.NotFound))
+
+	UseLegacyHTTPStatus()
+	assert.Equal(t, http.StatusBadRequest, Error(codes.Internal, "query orders").HTTPStatus)
+	assert.Equal(t, http.StatusBadRequest, Error(codes.Unauthenticated, "token expired").HTTPStatus)
+}
@@ -1,6 +1,8 @@
 package e
 
 import (
+	"errors"
+
 	"google.golang.org/grpc/codes"
 )
 
@@ -8,20 +10,22 @@ const (
 	clientErrMsg = "bad request"
 )
 
-// WrapError ...
+// WrapError returns the Status in the chain of err, or a "bad request" one caused by err.
 func WrapError(err error) *Status {
-	if stt, ok := err.(*Status); ok {
+	var stt *Status
+	if errors.As(err, &stt) {
 		return stt
 	}
 
-	return Error(codes.InvalidArgument, clientErrMsg)
+	return Error(codes.InvalidArgument, clientErrMsg).WithCause(err)
 }
 
-// WrapErrorf ...
+// WrapErrorf returns the Status in the chain of err, or an InvalidArgument one with the message caused by err.
 func WrapErrorf(err error, format string, args ...interface{}) *Status {
-	if stt, ok := err.(*Status); ok {
+	var stt *Status
+	if errors.As(err, &stt) {
 		return stt
 	}
 
-	return Errorf(codes.InvalidArgument, format, args...)
+	return Errorf(codes.InvalidArgument, format, args...).WithCause(err)
 }
@@ -0,0 +1,435 @@
+package gatewayopt
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const csvContentType = "text/csv"
+
+// CSVMarshaler is custom Marshaler that writes responses as text/csv.
+// A response having exactly one repeated message field is written as one row per element,
+// any other response, including every message of a server stream, as a single row.
+// The header row holds the json names of the fields, nested messages are flattened into
+// dotted columns like "customer.name", repeated and map fields are written as JSON.
+// Values which are not messages, e.g. a scalar response_body, are written in a single "value" column.
+// Errors are written as JSON. Use it together with CSVMiddleware, which selects columns with
+// the `fields` query parameter or the X-Fields header, like FieldMaskMiddleware, and writes
+// a single header row for streams:
+//
+//	GET /v1/orders?fields=orders.id,orders.customer.name
+//	Accept: text/csv
+func CSVMarshaler() runtime.ServeMuxOption {
+	m := &csvMarshaler{
+		JSONPb: &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: true},
+		},
+	}
+	return func(mux *runtime.ServeMux) {
+		runtime.WithMarshalerOption(csvContentType, m)(mux)
+		runtime.WithForwardResponseOption(m.forwardResponse)(mux)
+	}
+}
+
+type csvMarshaler struct {
+	*runtime.JSONPb
+}
+
+// csvRequest is the column selection of a request read by CSVMiddleware.
+type csvRequest struct {
+	fields        []string
+	headerWritten bool
+
+	// body replaces the next write of the marshaled response, see csvResponseWriter
+	body    []byte
+	replace bool
+}
+
+type csvRequestKey struct{}
+
+// forwardResponse encodes the response with the columns of its request, as Marshal only sees the response.
+func (m *csvMarshaler) forwardResponse(ctx context.Context, _ http.ResponseWriter, resp proto.Message) error {
+	req, ok := ctx.Value(csvRequestKey{}).(*csvRequest)
+	if !ok || resp == nil || isErrorResponse(resp) {
+		return nil
+	}
+	var v interface{} = resp
+	if rb, ok := resp.(interface{ XXX_ResponseBody() interface{} }); ok {
+		v = rb.XXX_ResponseBody()
+	}
+
+	var buf bytes.Buffer
+	if err := m.encode(&buf, v, req.fields, !req.headerWritten); err != nil {
+		return err
+	}
+	req.headerWritten = true
+	req.body, req.replace = buf.Bytes(), true
+	return nil
+}
+
+func (m *csvMarshaler) ContentType(v interface{}) string {
+	if isErrorResponse(v) {
+		return m.JSONPb.ContentType(v)
+	}
+	return csvContentType + "; charset=utf-8"
+}
+
+func (m *csvMarshaler) Marshal(v interface{}) ([]byte, error) {
+	if isErrorResponse(v) {
+		buf, err := m.JSONPb.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return append(buf, '\n'), nil
+	}
+
+	var buf bytes.Buffer
+	if err := m.encode(&buf, v, nil, true); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (m *csvMarshaler) Unmarshal(_ []byte, _ interface{}) error {
+	return status.Error(codes.InvalidArgument, "csv request bodies are not supported")
+}
+
+func (m *csvMarshaler) NewDecoder(_ io.Reader) runtime.Decoder {
+	return runtime.DecoderFunc(func(v interface{}) error {
+		return m.Unmarshal(nil, v)
+	})
+}
+
+func (m *csvMarshaler) NewEncoder(w io.Writer) runtime.Encoder {
+	return runtime.EncoderFunc(func(v interface{}) error {
+		if isErrorResponse(v) {
+			return m.JSONPb.NewEncoder(w).Encode(v)
+		}
+		return m.encode(w, v, nil, true)
+	})
+}
+
+// encode writes v as CSV with the selected fields, all columns when none are.
+func (m *csvMarshaler) encode(w io.Writer, v interface{}, fields []string, header bool) error {
+	if record, ok := v.(map[string]interface{}); ok {
+		// stream records produced by runtime.ForwardResponseStream
+		v = record["result"]
+	}
+
+	cw := csv.NewWriter(w)
+	md, rows, list, ok := csvTable(v)
+	if !ok {
+		text, isString := v.(string)
+		if !isString {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			text = string(b)
+		}
+		if header {
+			if err := cw.Write([]string{"value"}); err != nil {
+				return err
+			}
+		}
+		if err := cw.Write([]string{text}); err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	columns := csvColumns(md, "", "", nil)
+	if len(fields) > 0 {
+		columns = selectCSVColumns(columns, fields, list)
+	}
+	if header {
+		names := make([]string, len(columns))
+		for i, c := range columns {
+			names[i] = c.name
+		}
+		if err := cw.Write(names); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, c := range columns {
+			text, err := c.text(row)
+			if err != nil {
+				return err
+			}
+			record[i] = text
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (m *csvMarshaler) Delimiter() []byte {
+	// every record ends with its own line break
+	return nil
+}
+
+// csvTable returns the rows of a message, see csvRows, or of a slice of messages, along with
+// their descriptor and the repeated field holding them. ok is false for other values.
+func csvTable(v interface{}) (md protoreflect.MessageDescriptor, rows []protoreflect.Message, list protoreflect.FieldDescriptor, ok bool) {
+	if msg, isMsg := v.(proto.Message); isMsg {
+		md, rows, list = csvRows(msg.ProtoReflect())
+		return md, rows, list, true
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, nil, nil, false
+	}
+	elem, isMsg := reflect.Zero(rv.Type().Elem()).Interface().(proto.Message)
+	if !isMsg {
+		return nil, nil, nil, false
+	}
+	rows = make([]protoreflect.Message, rv.Len())
+	for i := range rows {
+		rows[i] = rv.Index(i).Interface().(proto.Message).ProtoReflect()
+	}
+	return elem.ProtoReflect().Descriptor(), rows, nil, true
+}
+
+// isErrorResponse reports google.rpc.Status responses and stream error chunks.
+func isErrorResponse(v interface{}) bool {
+	switch v := v.(type) {
+	case map[string]proto.Message:
+		return true
+	case proto.Message:
+		return v.ProtoReflect().Descriptor().FullName() == "google.rpc.Status"
+	}
+	return false
+}
+
+// csvRows returns the elements of the only repeated message field of msg, or msg itself,
+// along with their descriptor and that field.
+func csvRows(msg protoreflect.Message) (protoreflect.MessageDescriptor, []protoreflect.Message, protoreflect.FieldDescriptor) {
+	var list protoreflect.FieldDescriptor
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !fd.IsList() || fd.Message() == nil {
+			continue
+		}
+		if list != nil {
+			return msg.Descriptor(), []protoreflect.Message{msg}, nil
+		}
+		list = fd
+	}
+	if list == nil {
+		return msg.Descriptor(), []protoreflect.Message{msg}, nil
+	}
+
+	l := msg.Get(list).List()
+	rows := make([]protoreflect.Message, l.Len())
+	for i := range rows {
+		rows[i] = l.Get(i).Message()
+	}
+	return list.Message(), rows, list
+}
+
+type csvColumn struct {
+	name      string
+	protoName string
+	path      []protoreflect.FieldDescriptor
+}
+
+// csvColumns flattens md into columns, nested messages which are neither well known types
+// nor recursive are expanded into their fields.
+func csvColumns(md protoreflect.MessageDescriptor, prefix, protoPrefix string, parents []protoreflect.FullName) []csvColumn {
+	parents = append(parents, md.FullName())
+
+	var columns []csvColumn
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name, protoName := prefix+fd.JSONName(), protoPrefix+string(fd.Name())
+		if fd.Message() != nil && !fd.IsList() && !fd.IsMap() && !IsWellKnownType(fd.Message()) && !containsName(parents, fd.Message().FullName()) {
+			for _, c := range csvColumns(fd.Message(), name+".", protoName+".", parents) {
+				c.path = append([]protoreflect.FieldDescriptor{fd}, c.path...)
+				columns = append(columns, c)
+			}
+			continue
+		}
+		columns = append(columns, csvColumn{name: name, protoName: protoName, path: []protoreflect.FieldDescriptor{fd}})
+	}
+	return columns
+}
+
+// selectCSVColumns returns the columns of the field mask paths, in their order. A path selects
+// the column of that name or all the columns below it, unknown ones staying empty.
+// Paths may start with the repeated field holding the rows, so that the same mask prunes the response.
+func selectCSVColumns(columns []csvColumn, paths []string, list protoreflect.FieldDescriptor) []csvColumn {
+	var selected []csvColumn
+	seen := map[string]bool{}
+	add := func(c csvColumn) {
+		if !seen[c.name] {
+			seen[c.name] = true
+			selected = append(selected, c)
+		}
+	}
+
+	for _, path := range paths {
+		if list != nil {
+			if path == list.JSONName() || path == string(list.Name()) {
+				for _, c := range columns {
+					add(c)
+				}
+				continue
+			}
+			path = strings.TrimPrefix(strings.TrimPrefix(path, list.JSONName()+"."), string(list.Name())+".")
+		}
+
+		matched := false
+		for _, c := range columns {
+			if c.matches(path) {
+				add(c)
+				matched = true
+			}
+		}
+		if !matched {
+			add(csvColumn{name: path})
+		}
+	}
+	return selected
+}
+
+func (c csvColumn) matches(path string) bool {
+	for _, name := range []string{c.name, c.protoName} {
+		if name == path || strings.HasPrefix(name, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func containsName(names []protoreflect.FullName, name protoreflect.FullName) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c csvColumn) text(msg protoreflect.Message) (string, error) {
+	if len(c.path) == 0 {
+		// a selected column the message does not have
+		return "", nil
+	}
+	for _, fd := range c.path[:len(c.path)-1] {
+		if !msg.Has(fd) {
+			return "", nil
+		}
+		msg = msg.Get(fd).Message()
+	}
+
+	fd := c.path[len(c.path)-1]
+	switch {
+	case fd.IsList() || fd.IsMap():
+		return csvJSONField(msg, fd)
+	case fd.HasPresence() && !msg.Has(fd):
+		return "", nil
+	case fd.Message() != nil && IsWellKnownType(fd.Message()):
+		return wellKnownText(msg.Get(fd).Message())
+	case fd.Message() != nil:
+		b, err := protojson.Marshal(msg.Get(fd).Message().Interface())
+		return string(b), err
+	}
+	return scalarText(fd, msg.Get(fd)), nil
+}
+
+// csvJSONField returns the protojson value of a single field of msg.
+func csvJSONField(msg protoreflect.Message, fd protoreflect.FieldDescriptor) (string, error) {
+	if !msg.Has(fd) {
+		if fd.IsMap() {
+			return "{}", nil
+		}
+		return "[]", nil
+	}
+	m := msg.Type().New()
+	m.Set(fd, msg.Get(fd))
+	b, err := protojson.Marshal(m.Interface())
+	if err != nil {
+		return "", err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return "", err
+	}
+	return string(fields[fd.JSONName()]), nil
+}
+
+// CSVMiddleware completes CSVMarshaler for requests accepting text/csv, the way grpc-gateway picks
+// the marshaler: an Accept header of exactly "text/csv", e.g. set by ContentNegotiationMiddleware.
+// It keeps the columns of the comma separated `fields` query parameter or X-Fields header, in that order,
+// unknown ones staying empty, and writes the header row once for the messages of a stream.
+func CSVMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !acceptsExactly(r, csvContentType) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		req := &csvRequest{fields: requestFieldMask(r)}
+		next.ServeHTTP(&csvResponseWriter{ResponseWriter: w, req: req}, r.WithContext(context.WithValue(r.Context(), csvRequestKey{}, req)))
+	})
+}
+
+// acceptsExactly reports whether one of the Accept headers of r is mime, as runtime.MarshalerForRequest matches them.
+func acceptsExactly(r *http.Request, mime string) bool {
+	for _, v := range r.Header.Values("Accept") {
+		if v == mime {
+			return true
+		}
+	}
+	return false
+}
+
+// csvResponseWriter writes the response encoded by the forward response option of CSVMarshaler
+// in place of the one marshaled without the request.
+type csvResponseWriter struct {
+	http.ResponseWriter
+	req *csvRequest
+}
+
+func (w *csvResponseWriter) Write(p []byte) (int, error) {
+	if !w.req.replace {
+		return w.ResponseWriter.Write(p)
+	}
+	w.req.replace = false
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), csvContentType) {
+		// an error raised after the response was encoded
+		return w.ResponseWriter.Write(p)
+	}
+	if _, err := w.ResponseWriter.Write(w.req.body); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *csvResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
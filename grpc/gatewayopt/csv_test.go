@@ -0,0 +1,155 @@
+package gatewayopt
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestCSVMarshaler(t *testing.T) {
+	m := &csvMarshaler{JSONPb: &runtime.JSONPb{}}
+
+	buf, err := m.Marshal(&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{
+		{Subject: "user:1", Description: "daily limit, exceeded"},
+		{Subject: "user:2"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "subject,description\nuser:1,\"daily limit, exceeded\"\nuser:2,\n", string(buf))
+
+	buf, err = m.Marshal(&errdetails.QuotaFailure{})
+	require.NoError(t, err)
+	assert.Equal(t, "subject,description\n", string(buf))
+
+	// nested messages are flattened
+	md := newCSVTestMessage(t)
+	order := dynamicpb.NewMessage(md)
+	order.Set(md.Fields().ByName("id"), protoreflect.ValueOfString("O1"))
+	customer := order.Mutable(md.Fields().ByName("customer")).Message()
+	customer.Set(md.Fields().ByName("customer").Message().Fields().ByName("name"), protoreflect.ValueOfString("Tiki"))
+	buf, err = m.Marshal(order)
+	require.NoError(t, err)
+	assert.Equal(t, "id,customer.name\nO1,Tiki\n", string(buf))
+}
+
+// newCSVTestMessage builds the descriptor of:
+//
+//	message Order {
+//	  string id = 1;
+//	  Customer customer = 2;
+//	  message Customer { string name = 1; }
+//	}
+func newCSVTestMessage(t *testing.T) protoreflect.MessageDescriptor {
+	customer := testField("customer", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	customer.TypeName = proto.String(".test.Order.Customer")
+	return newTestMessage(t, "csv", &descriptorpb.DescriptorProto{
+		Name:  proto.String("Order"),
+		Field: []*descriptorpb.FieldDescriptorProto{testField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING), customer},
+		NestedType: []*descriptorpb.DescriptorProto{{
+			Name:  proto.String("Customer"),
+			Field: []*descriptorpb.FieldDescriptorProto{testField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)},
+		}},
+	})
+}
+
+// newCSVTestHandler serves the stream of msgs, or the first one when unary, behind CSVMiddleware.
+func newCSVTestHandler(stream bool, msgs ...proto.Message) http.Handler {
+	mux := runtime.NewServeMux(CSVMarshaler())
+	return CSVMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{})
+		if !stream {
+			runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, msgs[0], mux.GetForwardResponseOptions()...)
+			return
+		}
+		runtime.ForwardResponseStream(ctx, mux, outbound, w, r, func() (proto.Message, error) {
+			if len(msgs) == 0 {
+				return nil, io.EOF
+			}
+			msg := msgs[0]
+			msgs = msgs[1:]
+			return msg, nil
+		}, mux.GetForwardResponseOptions()...)
+	}))
+}
+
+func TestCSVMiddleware_Stream(t *testing.T) {
+	h := newCSVTestHandler(true,
+		&errdetails.ErrorInfo{Reason: "A", Domain: "tiki.vn", Metadata: map[string]string{"k": "v"}},
+		&errdetails.ErrorInfo{Reason: "B", Domain: "tiki.vn"},
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/?fields=reason,metadata,missing", nil)
+	r.Header.Set("Accept", "text/csv")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "reason,metadata,missing\nA,\"{\"\"k\"\":\"\"v\"\"}\",\nB,{},\n", w.Body.String())
+}
+
+// errorInfoReason streams the reason of an ErrorInfo as its response_body.
+type errorInfoReason struct {
+	*errdetails.ErrorInfo
+}
+
+func (r errorInfoReason) XXX_ResponseBody() interface{} {
+	return r.Reason
+}
+
+func TestCSVMiddleware_StreamResponseBody(t *testing.T) {
+	h := newCSVTestHandler(true,
+		errorInfoReason{&errdetails.ErrorInfo{Reason: "A"}},
+		errorInfoReason{&errdetails.ErrorInfo{Reason: "B"}},
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/csv")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, "value\nA\nB\n", w.Body.String())
+}
+
+func TestCSVMiddleware_Fields(t *testing.T) {
+	resp := &errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{
+		{Subject: "user:1", Description: "daily limit"},
+		{Subject: "user:2"},
+	}}
+
+	tests := []struct {
+		name   string
+		accept string
+		fields string
+		want   string
+	}{
+		{"field mask path", "text/csv", "violations.subject", "subject\nuser:1\nuser:2\n"},
+		{"row path", "text/csv", "description", "description\ndaily limit\n\n"},
+		{"whole list", "text/csv", "violations", "subject,description\nuser:1,daily limit\nuser:2,\n"},
+		// grpc-gateway matches the Accept header exactly, the default marshaler writes JSON
+		{"not exactly text/csv", "text/csv;q=0.9", "violations.subject", `{"violations":[{"subject":"user:1","description":"daily limit"},{"subject":"user:2","description":""}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?fields="+tt.fields, nil)
+			r.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			newCSVTestHandler(false, resp).ServeHTTP(w, r)
+
+			if tt.accept != "text/csv" {
+				assert.JSONEq(t, tt.want, w.Body.String())
+				return
+			}
+			assert.Equal(t, tt.want, w.Body.String())
+		})
+	}
+}
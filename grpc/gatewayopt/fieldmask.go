@@ -0,0 +1,430 @@
+package gatewayopt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+const (
+	fieldMaskQueryParam   = "fields"
+	fieldMaskHeader       = "X-Fields"
+	fieldMaskMetadataKey  = "x-fields"
+	fieldMaskForwardedKey = runtime.MetadataHeaderPrefix + fieldMaskHeader
+)
+
+type fieldMaskKey struct{}
+
+// fieldMaskRequest is the mask read by FieldMaskMiddleware.
+type fieldMaskRequest struct {
+	paths    []string
+	metadata bool
+
+	// the pruned response about to be written, see fieldMaskResponseWriter
+	stream  bool
+	pending bool
+	md      protoreflect.MessageDescriptor
+	tree    fieldMaskTree
+}
+
+type fieldMaskConfig struct {
+	metadata bool
+}
+
+// FieldMaskOption configures FieldMaskMiddleware.
+type FieldMaskOption func(*fieldMaskConfig)
+
+// WithFieldMaskMetadata also passes the mask to the handler as the "x-fields" incoming metadata,
+// read it with FieldMaskFromContext. The mask is sent by FieldMaskUnaryClientInterceptor and
+// FieldMaskStreamClientInterceptor once validated.
+func WithFieldMaskMetadata() FieldMaskOption {
+	return func(c *fieldMaskConfig) {
+		c.metadata = true
+	}
+}
+
+// FieldMaskMiddleware reads a comma separated FieldMask from the `fields` query parameter
+// or the X-Fields header, e.g. "id,customer.name", for FieldMaskResponse to prune the response with.
+// Install FieldMaskUnaryClientInterceptor and FieldMaskStreamClientInterceptor on the connection of
+// the gateway so that invalid masks are answered with 400 before the method is called.
+func FieldMaskMiddleware(opts ...FieldMaskOption) func(http.Handler) http.Handler {
+	c := &fieldMaskConfig{}
+	for _, f := range opts {
+		f(c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the mask only reaches handlers through the interceptors, once validated
+			r.Header.Del(fieldMaskForwardedKey)
+
+			paths := requestFieldMask(r)
+			if len(paths) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			fm := &fieldMaskRequest{paths: paths, metadata: c.metadata}
+			next.ServeHTTP(&fieldMaskResponseWriter{ResponseWriter: w, fm: fm}, r.WithContext(context.WithValue(r.Context(), fieldMaskKey{}, fm)))
+		})
+	}
+}
+
+// FieldMaskUnaryClientInterceptor validates the mask read by FieldMaskMiddleware against the response
+// of the called method, failing with InvalidArgument before calling it, and passes the mask as metadata
+// with WithFieldMaskMetadata. Install it on the connection the gateway calls the methods through.
+func FieldMaskUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, err := fieldMaskOutgoingContext(ctx, method)
+		if err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// FieldMaskStreamClientInterceptor is the stream counterpart of FieldMaskUnaryClientInterceptor.
+func FieldMaskStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, err := fieldMaskOutgoingContext(ctx, method)
+		if err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+func fieldMaskOutgoingContext(ctx context.Context, method string) (context.Context, error) {
+	fm, ok := ctx.Value(fieldMaskKey{}).(*fieldMaskRequest)
+	if !ok {
+		return ctx, nil
+	}
+	md, ok := methodOutput(method)
+	if !ok {
+		// FieldMaskResponse validates the mask against the response
+		return ctx, nil
+	}
+	if _, err := newFieldMaskTree(md, fm.paths); err != nil {
+		return nil, err
+	}
+	if fm.metadata {
+		ctx = metadata.AppendToOutgoingContext(ctx, fieldMaskMetadataKey, strings.Join(fm.paths, ","))
+	}
+	return ctx, nil
+}
+
+// methodOutput returns the response descriptor of a full method name like "/pb.HealthService/Liveness".
+func methodOutput(method string) (protoreflect.MessageDescriptor, bool) {
+	method = strings.TrimPrefix(method, "/")
+	i := strings.LastIndex(method, "/")
+	if i < 0 {
+		return nil, false
+	}
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(method[:i]))
+	if err != nil {
+		return nil, false
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, false
+	}
+	m := sd.Methods().ByName(protoreflect.Name(method[i+1:]))
+	if m == nil {
+		return nil, false
+	}
+	return m.Output(), true
+}
+
+// FieldMaskResponse prunes gateway responses to the FieldMask read by FieldMaskMiddleware.
+// Paths use json or proto field names and may go through repeated and map message fields,
+// in which case they apply to every element. Unknown paths are rejected with InvalidArgument.
+// JSON responses only hold the fields of the mask, even when the marshaler emits unpopulated fields.
+func FieldMaskResponse() runtime.ServeMuxOption {
+	fn := func(ctx context.Context, _ http.ResponseWriter, resp proto.Message) error {
+		fm, ok := ctx.Value(fieldMaskKey{}).(*fieldMaskRequest)
+		if !ok {
+			return nil
+		}
+		if resp == nil {
+			// runtime.ForwardResponseStream calls the options once before the first message
+			fm.stream = true
+			return nil
+		}
+
+		msg := resp.ProtoReflect()
+		tree, err := newFieldMaskTree(msg.Descriptor(), fm.paths)
+		if err != nil {
+			return err
+		}
+		tree.prune(msg)
+		if _, ok := resp.(interface{ XXX_ResponseBody() interface{} }); !ok {
+			fm.md, fm.tree, fm.pending = msg.Descriptor(), tree, true
+		}
+		return nil
+	}
+	return runtime.WithForwardResponseOption(fn)
+}
+
+// FieldMaskFromContext returns the mask passed by FieldMaskMiddleware with WithFieldMaskMetadata.
+func FieldMaskFromContext(ctx context.Context) (*fieldmaskpb.FieldMask, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	paths := parseFieldMask(strings.Join(md.Get(fieldMaskMetadataKey), ","))
+	if len(paths) == 0 {
+		return nil, false
+	}
+	return &fieldmaskpb.FieldMask{Paths: paths}, true
+}
+
+// requestFieldMask returns the paths of the `fields` query parameter, or of the X-Fields header without it.
+func requestFieldMask(r *http.Request) []string {
+	value := strings.Join(r.URL.Query()[fieldMaskQueryParam], ",")
+	if value == "" {
+		value = strings.Join(r.Header.Values(fieldMaskHeader), ",")
+	}
+	return parseFieldMask(value)
+}
+
+func parseFieldMask(value string) []string {
+	var paths []string
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// fieldMaskTree holds the kept fields by proto name, an empty subtree keeps the whole field.
+type fieldMaskTree map[protoreflect.Name]fieldMaskTree
+
+func newFieldMaskTree(md protoreflect.MessageDescriptor, paths []string) (fieldMaskTree, error) {
+	tree := fieldMaskTree{}
+	for _, path := range paths {
+		node, desc := tree, md
+		names := strings.Split(path, ".")
+		for i, name := range names {
+			if desc == nil {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid field mask path %q", path)
+			}
+			fd := fieldByName(desc, name)
+			if fd == nil {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid field mask path %q", path)
+			}
+
+			if i == len(names)-1 {
+				node[fd.Name()] = fieldMaskTree{}
+				break
+			}
+			sub, ok := node[fd.Name()]
+			if ok && len(sub) == 0 {
+				// the whole field is already kept
+				break
+			}
+			if !ok {
+				sub = fieldMaskTree{}
+				node[fd.Name()] = sub
+			}
+			node = sub
+
+			desc = fd.Message()
+			if fd.IsMap() {
+				desc = fd.MapValue().Message()
+			}
+		}
+	}
+	return tree, nil
+}
+
+func (t fieldMaskTree) prune(msg protoreflect.Message) {
+	var cleared []protoreflect.FieldDescriptor
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		sub, ok := t[fd.Name()]
+		switch {
+		case !ok:
+			cleared = append(cleared, fd)
+		case len(sub) == 0:
+		case fd.IsList():
+			for i, l := 0, v.List(); i < l.Len(); i++ {
+				sub.prune(l.Get(i).Message())
+			}
+		case fd.IsMap():
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				sub.prune(mv.Message())
+				return true
+			})
+		default:
+			sub.prune(v.Message())
+		}
+		return true
+	})
+	for _, fd := range cleared {
+		msg.Clear(fd)
+	}
+}
+
+// fieldMaskResponseWriter drops the fields outside the mask from the JSON of the pruned response,
+// which holds them with their default value when the marshaler emits unpopulated fields.
+type fieldMaskResponseWriter struct {
+	http.ResponseWriter
+	fm *fieldMaskRequest
+}
+
+func (w *fieldMaskResponseWriter) Write(p []byte) (int, error) {
+	if !w.fm.pending {
+		return w.ResponseWriter.Write(p)
+	}
+	w.fm.pending = false
+	if mt, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type")); mt != "application/json" {
+		return w.ResponseWriter.Write(p)
+	}
+	filtered, ok := w.fm.filterJSON(p)
+	if !ok {
+		// not the response, e.g. an error raised after it was pruned
+		return w.ResponseWriter.Write(p)
+	}
+	if _, err := w.ResponseWriter.Write(filtered); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *fieldMaskResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// filterJSON filters the response, or the {"result": ...} record of a stream.
+func (fm *fieldMaskRequest) filterJSON(data []byte) ([]byte, bool) {
+	if !fm.stream {
+		return fm.tree.filterJSON(fm.md, data)
+	}
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(data, &record); err != nil || len(record) != 1 || record["result"] == nil {
+		return nil, false
+	}
+	result, ok := fm.tree.filterJSON(fm.md, record["result"])
+	if !ok {
+		return nil, false
+	}
+	return append(append([]byte(`{"result":`), result...), '}'), true
+}
+
+// filterJSON keeps the fields of t in data, the JSON object of a md message. ok is false when
+// data is not such an object, e.g. a well known type.
+func (t fieldMaskTree) filterJSON(md protoreflect.MessageDescriptor, data []byte) (_ []byte, ok bool) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, false
+	}
+	for k := range obj {
+		if fieldByName(md, k) == nil {
+			return nil, false
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		sub, kept := t[fd.Name()]
+		if !kept {
+			continue
+		}
+		key := fd.JSONName()
+		raw, found := obj[key]
+		if !found {
+			key = string(fd.Name())
+			if raw, found = obj[key]; !found {
+				continue
+			}
+		}
+		if len(sub) > 0 {
+			raw = sub.filterJSONValue(fd, raw)
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(raw)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), true
+}
+
+// filterJSONValue filters the value of a message field, or of every element of a repeated or map one.
+func (t fieldMaskTree) filterJSONValue(fd protoreflect.FieldDescriptor, raw json.RawMessage) json.RawMessage {
+	filter := func(md protoreflect.MessageDescriptor, raw json.RawMessage) json.RawMessage {
+		if filtered, ok := t.filterJSON(md, raw); ok {
+			return filtered
+		}
+		return raw
+	}
+
+	switch {
+	case fd.IsMap():
+		md := fd.MapValue().Message()
+		var m map[string]json.RawMessage
+		if md == nil || json.Unmarshal(raw, &m) != nil {
+			return raw
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			name, _ := json.Marshal(k)
+			buf.Write(name)
+			buf.WriteByte(':')
+			buf.Write(filter(md, m[k]))
+		}
+		buf.WriteByte('}')
+		return buf.Bytes()
+	case fd.IsList():
+		var l []json.RawMessage
+		if fd.Message() == nil || json.Unmarshal(raw, &l) != nil {
+			return raw
+		}
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i, v := range l {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(filter(fd.Message(), v))
+		}
+		buf.WriteByte(']')
+		return buf.Bytes()
+	case fd.Message() != nil:
+		return filter(fd.Message(), raw)
+	}
+	return raw
+}
@@ -0,0 +1,130 @@
+package gatewayopt
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tikivn/tikit-go-kit/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFieldMaskResponse(t *testing.T) {
+	mux := runtime.NewServeMux(ProtoJSONMarshaler(), FieldMaskResponse())
+
+	var forwarded string
+	h := FieldMaskMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get("Grpc-Metadata-X-Fields")
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{})
+		resp := &errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{
+			{Subject: "user:1", Description: "daily limit"},
+			{Subject: "user:2", Description: "monthly limit"},
+		}}
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, resp, mux.GetForwardResponseOptions()...)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/?fields=violations.subject", nil)
+	r.Header.Set("Grpc-Metadata-X-Fields", "violations")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"violations":[{"subject":"user:1"},{"subject":"user:2"}]}`, w.Body.String())
+	assert.Empty(t, forwarded, "callers cannot pass the mask metadata themselves")
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Fields", "violations.unknown")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestFieldMaskResponse_Stream(t *testing.T) {
+	mux := runtime.NewServeMux(ProtoJSONMarshaler(), FieldMaskResponse())
+	h := FieldMaskMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{})
+		msgs := []proto.Message{
+			&errdetails.QuotaFailure_Violation{Subject: "user:1", Description: "daily limit"},
+			&errdetails.QuotaFailure_Violation{Subject: "user:2", Description: "monthly limit"},
+		}
+		runtime.ForwardResponseStream(ctx, mux, outbound, w, r, func() (proto.Message, error) {
+			if len(msgs) == 0 {
+				return nil, io.EOF
+			}
+			msg := msgs[0]
+			msgs = msgs[1:]
+			return msg, nil
+		}, mux.GetForwardResponseOptions()...)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?fields=subject", nil))
+	assert.Equal(t, "{\"result\":{\"subject\":\"user:1\"}}\n{\"result\":{\"subject\":\"user:2\"}}\n", w.Body.String())
+}
+
+func TestFieldMaskUnaryClientInterceptor(t *testing.T) {
+	call := func(t *testing.T, query string, opts ...FieldMaskOption) (called bool, md metadata.MD, err error) {
+		r := httptest.NewRequest(http.MethodGet, "/health?"+query, nil)
+		FieldMaskMiddleware(opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+				called = true
+				md, _ = metadata.FromOutgoingContext(ctx)
+				return nil
+			}
+			err = FieldMaskUnaryClientInterceptor()(r.Context(), "/pb.HealthService/Liveness", &pb.LivenessRequest{}, &pb.LivenessResponse{}, nil, invoker)
+		})).ServeHTTP(httptest.NewRecorder(), r)
+		return called, md, err
+	}
+
+	t.Run("valid mask", func(t *testing.T) {
+		called, md, err := call(t, "fields=message", WithFieldMaskMetadata())
+		require.NoError(t, err)
+		assert.True(t, called)
+		assert.Equal(t, []string{"message"}, md.Get(fieldMaskMetadataKey))
+	})
+
+	t.Run("without metadata", func(t *testing.T) {
+		called, md, err := call(t, "fields=message")
+		require.NoError(t, err)
+		assert.True(t, called)
+		assert.Empty(t, md.Get(fieldMaskMetadataKey))
+	})
+
+	t.Run("invalid mask", func(t *testing.T) {
+		called, _, err := call(t, "fields=unknown", WithFieldMaskMetadata())
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.False(t, called, "invalid masks never reach the handler")
+	})
+}
+
+func TestFieldMaskTree(t *testing.T) {
+	msg := &errdetails.ErrorInfo{Reason: "R", Domain: "tiki.vn", Metadata: map[string]string{"k": "v"}}
+	tree, err := newFieldMaskTree(msg.ProtoReflect().Descriptor(), []string{"reason", "metadata"})
+	require.NoError(t, err)
+	tree.prune(msg.ProtoReflect())
+	assert.True(t, proto.Equal(&errdetails.ErrorInfo{Reason: "R", Metadata: map[string]string{"k": "v"}}, msg))
+
+	_, err = newFieldMaskTree(msg.ProtoReflect().Descriptor(), []string{"reason.length"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestFieldMaskFromContext(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-fields", "id,customer.name"))
+	mask, ok := FieldMaskFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, []string{"id", "customer.name"}, mask.GetPaths())
+
+	_, ok = FieldMaskFromContext(context.Background())
+	assert.False(t, ok)
+}
@@ -0,0 +1,356 @@
+package gatewayopt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/runtime/protoimpl"
+)
+
+const (
+	formContentType = "application/x-www-form-urlencoded"
+	// maxFormListIndex bounds the indexes of repeated fields, the list grows up to the index.
+	maxFormListIndex = 999
+)
+
+// FormOption configures FormURLEncodedMarshaler.
+type FormOption func(*formMarshaler)
+
+// WithFormFieldAliases maps form keys sent by a partner to field paths of the request,
+// e.g. {"partnerCode": "partner_code", "buyer": "customer.name"}.
+func WithFormFieldAliases(aliases map[string]string) FormOption {
+	return func(m *formMarshaler) {
+		m.aliases = aliases
+	}
+}
+
+// WithFormURLEncodedResponse marshals responses as x-www-form-urlencoded, with keys
+// written the way they are read. Errors are still marshaled as JSON.
+func WithFormURLEncodedResponse() FormOption {
+	return func(m *formMarshaler) {
+		m.response = true
+	}
+}
+
+func (j *formMarshaler) ContentType(v interface{}) string {
+	if !j.response || isErrorResponse(v) {
+		return j.JSONPb.ContentType(v)
+	}
+	return formContentType
+}
+
+func (j *formMarshaler) Marshal(v interface{}) ([]byte, error) {
+	if !j.response || isErrorResponse(v) {
+		return j.JSONPb.Marshal(v)
+	}
+
+	var buf bytes.Buffer
+	if err := j.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (j *formMarshaler) NewEncoder(w io.Writer) runtime.Encoder {
+	if !j.response {
+		return j.JSONPb.NewEncoder(w)
+	}
+	return runtime.EncoderFunc(func(v interface{}) error {
+		if isErrorResponse(v) {
+			return j.JSONPb.NewEncoder(w).Encode(v)
+		}
+		if rec, ok := v.(map[string]interface{}); ok {
+			// stream records produced by runtime.ForwardResponseStream
+			v = rec["result"]
+		}
+		msg, ok := formMessage(v)
+		if !ok {
+			return fmt.Errorf("not proto message")
+		}
+
+		var pairs []string
+		if err := encodeForm(msg.ProtoReflect(), "", &pairs); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, strings.Join(pairs, "&"))
+		return err
+	})
+}
+
+// formMessage accepts messages generated by both protobuf APIs, the partner callbacks are often old ones.
+func formMessage(v interface{}) (proto.Message, bool) {
+	switch v := v.(type) {
+	case proto.Message:
+		return v, true
+	case protoiface.MessageV1:
+		return protoimpl.X.ProtoMessageV2Of(v), true
+	}
+	return nil, false
+}
+
+func populateForm(msg protoreflect.Message, values url.Values, aliases map[string]string) error {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	// sorted for deterministic errors
+	sort.Strings(keys)
+
+	plain := url.Values{}
+	for _, key := range keys {
+		path, aliased := aliases[key]
+		if !aliased {
+			path = key
+			if !strings.Contains(key, "[") && fieldByName(msg.Descriptor(), strings.Split(key, ".")[0]) != nil {
+				// keys the gateway understands are read like query parameters
+				plain[key] = values[key]
+				continue
+			}
+		}
+		tokens, err := parseFormKey(path)
+		if err != nil {
+			return err
+		}
+		if formFieldByName(msg.Descriptor(), tokens[0]) == nil {
+			// unknown keys are ignored like unknown query parameters
+			continue
+		}
+		if err := setFormValue(msg, tokens, values[key]); err != nil {
+			return fmt.Errorf("invalid form key %q: %w", key, err)
+		}
+	}
+	return runtime.PopulateQueryParameters(msg.Interface(), plain, &utilities.DoubleArray{})
+}
+
+// parseFormKey splits "a.b[c][]" into "a", "b", "c" and "" which appends to a list.
+func parseFormKey(key string) ([]string, error) {
+	var tokens []string
+	i := strings.IndexByte(key, '[')
+	if i < 0 {
+		i = len(key)
+	}
+	tokens = append(tokens, strings.Split(key[:i], ".")...)
+
+	for rest := key[i:]; rest != ""; {
+		end := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || end < 0 {
+			return nil, fmt.Errorf("invalid form key %q", key)
+		}
+		tokens = append(tokens, rest[1:end])
+		rest = rest[end+1:]
+		if strings.HasPrefix(rest, ".") {
+			// mixed notation, e.g. items[0].sku
+			next := strings.IndexByte(rest, '[')
+			if next < 0 {
+				next = len(rest)
+			}
+			tokens = append(tokens, strings.Split(rest[1:next], ".")...)
+			rest = rest[next:]
+		}
+	}
+	return tokens, nil
+}
+
+// formFieldByName looks a field up like fieldByName, then ignoring case, e.g. "PartnerCode" or "PARTNER_CODE".
+func formFieldByName(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	if fd := fieldByName(md, name); fd != nil {
+		return fd
+	}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if strings.EqualFold(fd.JSONName(), name) || strings.EqualFold(string(fd.Name()), name) {
+			return fd
+		}
+	}
+	return nil
+}
+
+func setFormValue(msg protoreflect.Message, tokens []string, values []string) error {
+	fd := formFieldByName(msg.Descriptor(), tokens[0])
+	if fd == nil {
+		return fmt.Errorf("unknown field %q of %s", tokens[0], msg.Descriptor().FullName())
+	}
+	rest := tokens[1:]
+
+	switch {
+	case fd.IsMap():
+		if len(rest) == 0 {
+			return fmt.Errorf("missing key of map field %s", fd.Name())
+		}
+		k, err := parseScalar(fd.MapKey(), rest[0])
+		if err != nil {
+			return err
+		}
+		m := msg.Mutable(fd).Map()
+		if fd.MapValue().Message() != nil && !IsWellKnownType(fd.MapValue().Message()) {
+			return setFormValue(m.Mutable(k.MapKey()).Message(), rest[1:], values)
+		}
+		text, err := singleFormValue(fd, values)
+		if err != nil {
+			return err
+		}
+		v, err := parseFormScalar(m.NewValue(), fd.MapValue(), text, rest[1:])
+		if err != nil {
+			return err
+		}
+		m.Set(k.MapKey(), v)
+		return nil
+
+	case fd.IsList():
+		l := msg.Mutable(fd).List()
+		if len(rest) > 0 && rest[0] == "" {
+			rest = rest[1:]
+		}
+		index := -1
+		if len(rest) > 0 {
+			n, err := strconv.Atoi(rest[0])
+			if err != nil || n < 0 || n > maxFormListIndex {
+				return status.Errorf(codes.InvalidArgument, "invalid index %q of repeated field %s", rest[0], fd.Name())
+			}
+			index, rest = n, rest[1:]
+			for l.Len() <= index {
+				l.Append(l.NewElement())
+			}
+		}
+
+		if fd.Message() != nil && !IsWellKnownType(fd.Message()) {
+			if index < 0 {
+				return fmt.Errorf("missing index of repeated field %s", fd.Name())
+			}
+			return setFormValue(l.Get(index).Message(), rest, values)
+		}
+		if index >= 0 {
+			text, err := singleFormValue(fd, values)
+			if err != nil {
+				return err
+			}
+			v, err := parseFormScalar(l.Get(index), fd, text, rest)
+			if err != nil {
+				return err
+			}
+			l.Set(index, v)
+			return nil
+		}
+		for _, s := range values {
+			v, err := parseFormScalar(l.NewElement(), fd, s, rest)
+			if err != nil {
+				return err
+			}
+			l.Append(v)
+		}
+		return nil
+
+	case fd.Message() != nil && !IsWellKnownType(fd.Message()):
+		if len(rest) == 0 {
+			return fmt.Errorf("missing field of message field %s", fd.Name())
+		}
+		return setFormValue(msg.Mutable(fd).Message(), rest, values)
+	}
+
+	text, err := singleFormValue(fd, values)
+	if err != nil {
+		return err
+	}
+	v, err := parseFormScalar(msg.NewField(fd), fd, text, rest)
+	if err != nil {
+		return err
+	}
+	msg.Set(fd, v)
+	return nil
+}
+
+// singleFormValue fails on repeated values for a single field, like runtime.PopulateQueryParameters.
+func singleFormValue(fd protoreflect.FieldDescriptor, values []string) (string, error) {
+	if len(values) > 1 {
+		return "", fmt.Errorf("too many values for field %q: %s", fd.Name(), strings.Join(values, ", "))
+	}
+	return values[0], nil
+}
+
+// parseFormScalar parses a scalar or well known type, zero is a new value for the latter.
+func parseFormScalar(zero protoreflect.Value, fd protoreflect.FieldDescriptor, text string, rest []string) (protoreflect.Value, error) {
+	if len(rest) > 0 {
+		return protoreflect.Value{}, fmt.Errorf("field %s has no subfields", fd.Name())
+	}
+	if fd.Message() != nil {
+		if err := parseWellKnownText(text, zero.Message()); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return zero, nil
+	}
+	return parseScalar(fd, text)
+}
+
+// encodeForm appends the fields of msg as escaped key=value pairs, in field order.
+func encodeForm(msg protoreflect.Message, prefix string, pairs *[]string) error {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		key := fd.JSONName()
+		if prefix != "" {
+			key = prefix + "[" + key + "]"
+		}
+
+		switch {
+		case fd.IsMap():
+			var keys []protoreflect.MapKey
+			msg.Get(fd).Map().Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+				keys = append(keys, k)
+				return true
+			})
+			sort.Slice(keys, func(a, b int) bool { return keys[a].String() < keys[b].String() })
+			for _, k := range keys {
+				if err := encodeFormValue(fd.MapValue(), msg.Get(fd).Map().Get(k), key+"["+k.String()+"]", pairs); err != nil {
+					return err
+				}
+			}
+		case fd.IsList():
+			l := msg.Get(fd).List()
+			for j := 0; j < l.Len(); j++ {
+				elem := key + "[]"
+				if fd.Message() != nil {
+					elem = key + "[" + strconv.Itoa(j) + "]"
+				}
+				if err := encodeFormValue(fd, l.Get(j), elem, pairs); err != nil {
+					return err
+				}
+			}
+		case fd.HasPresence() && !msg.Has(fd):
+		default:
+			if err := encodeFormValue(fd, msg.Get(fd), key, pairs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func encodeFormValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, key string, pairs *[]string) error {
+	text := ""
+	switch {
+	case fd.Message() != nil && IsWellKnownType(fd.Message()):
+		var err error
+		if text, err = wellKnownText(v.Message()); err != nil {
+			return err
+		}
+	case fd.Message() != nil:
+		return encodeForm(v.Message(), key, pairs)
+	default:
+		text = scalarText(fd, v)
+	}
+	*pairs = append(*pairs, url.QueryEscape(key)+"="+url.QueryEscape(text))
+	return nil
+}
@@ -2,44 +2,51 @@ package gatewayopt
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
-	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 // DefaultMarshaler return default grpc-gateway marshaler with additional support for emit empty field
+// google.api.HttpBody responses are written as raw bodies.
 func DefaultMarshaler() runtime.ServeMuxOption {
 	return runtime.WithMarshalerOption(runtime.MIMEWildcard,
-		&runtime.JSONPb{
+		newHTTPBodyMarshaler(&runtime.JSONPb{
 			MarshalOptions: protojson.MarshalOptions{UseEnumNumbers: true, EmitUnpopulated: true},
-		})
+		}))
 }
 
 // ProtoJSONMarshaler return the marshaler option with support serialization data with json_name specific
+// google.api.HttpBody responses are written as raw bodies.
 func ProtoJSONMarshaler() runtime.ServeMuxOption {
 	return runtime.WithMarshalerOption(runtime.MIMEWildcard,
-		&runtime.JSONPb{
+		newHTTPBodyMarshaler(&runtime.JSONPb{
 			MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: true},
-		})
+		}))
 }
 
 // Redirect support redirect endpoint for GRPC gateway by using server metadata
 // Example:
-//		redirect := metadata.Pairs(
-//			"Redirect", "https://example.com",
-//			"Redirect-Code", "308",
-//		)
-//		grpc.SendHeader(ctx, redirect)
 //
-// This will redirect endpoint to https://5kbps.io with code 308, by default redirect code is 301.
+//	redirect := metadata.Pairs(
+//		"Redirect", "https://example.com",
+//		"Redirect-Code", "308",
+//	)
+//	grpc.SendHeader(ctx, redirect)
+//
+// This will redirect endpoint to https://example.com with code 308, by default redirect code is 301.
+//
+// Deprecated: use SetRedirect with ResponseControl.
 func Redirect() runtime.ServeMuxOption {
 	fn := func(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
 		md, ok := runtime.ServerMetadataFromContext(ctx)
@@ -48,45 +55,67 @@ func Redirect() runtime.ServeMuxOption {
 		}
 
 		redirect := md.HeaderMD.Get("redirect")
-		redirectCode := md.HeaderMD.Get("redirect-code")
-		if len(redirect) > 0 {
-			w.Header().Set("Location", redirect[0])
-			if len(redirectCode) > 0 {
-				code, err := strconv.Atoi(redirectCode[0])
-				if err != nil {
-					return err
-				}
-				if code > 300 && code < 400 {
-					w.WriteHeader(code)
-				}
-			}
+		if len(redirect) == 0 {
+			return nil
+		}
 
-			w.WriteHeader(301)
+		code := http.StatusMovedPermanently
+		if redirectCode := md.HeaderMD.Get("redirect-code"); len(redirectCode) > 0 {
+			c, err := strconv.Atoi(redirectCode[0])
+			if err != nil {
+				return err
+			}
+			if c > 300 && c < 400 {
+				code = c
+			}
 		}
+		md.HeaderMD.Delete("redirect")
+		md.HeaderMD.Delete("redirect-code")
+
+		w.Header().Set("Location", redirect[0])
+		w.WriteHeader(code)
 		return nil
 	}
 	return runtime.WithForwardResponseOption(fn)
 }
 
-// FormURLEncodedMarshaler is custom Marshaler that supports reading x-www-form-urlencoded mine type
-func FormURLEncodedMarshaler() runtime.ServeMuxOption {
-	return runtime.WithMarshalerOption("application/x-www-form-urlencoded", &formMarshaler{
-		&runtime.JSONPb{
+// FormURLEncodedMarshaler is custom Marshaler that supports reading x-www-form-urlencoded mine type.
+// Keys are read like query parameters by runtime.PopulateQueryParameters. Aliased keys, bracketed keys
+// and keys matching a field only ignoring case may also address nested and repeated fields the way
+// partners commonly send them:
+//
+//	customer.name=A or customer[name]=A   nested message field
+//	tags=a&tags=b or tags[]=a&tags[]=b    repeated scalar field
+//	items[0][sku]=A&items[1][sku]=B       repeated message field
+//	labels[color]=red                     map field
+//
+// Indexes of repeated fields go up to 999. Unknown keys are ignored, unknown fields below a known one
+// and several values for a single field are rejected.
+// Responses are marshaled as JSON unless WithFormURLEncodedResponse is given.
+func FormURLEncodedMarshaler(opts ...FormOption) runtime.ServeMuxOption {
+	m := &formMarshaler{
+		JSONPb: &runtime.JSONPb{
 			MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: true},
 		},
-	})
+	}
+	for _, f := range opts {
+		f(m)
+	}
+	return runtime.WithMarshalerOption("application/x-www-form-urlencoded", m)
 }
 
 type formMarshaler struct {
 	*runtime.JSONPb
+	aliases  map[string]string
+	response bool
 }
 
 func (j *formMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
-	return runtime.DecoderFunc(func(v interface{}) error { return formDecoderFunc(r, v) })
+	return runtime.DecoderFunc(func(v interface{}) error { return j.formDecoderFunc(r, v) })
 }
 
-func formDecoderFunc(d io.Reader, v interface{}) error {
-	msg, ok := v.(proto.Message)
+func (j *formMarshaler) formDecoderFunc(d io.Reader, v interface{}) error {
+	msg, ok := formMessage(v)
 	if !ok {
 		return fmt.Errorf("not proto message")
 	}
@@ -98,10 +127,69 @@ func formDecoderFunc(d io.Reader, v interface{}) error {
 	if err != nil {
 		return err
 	}
-	filter := &utilities.DoubleArray{}
-	err = runtime.PopulateQueryParameters(msg, values, filter)
-	if err != nil {
-		return err
+	return populateForm(msg.ProtoReflect(), values, j.aliases)
+}
+
+// IsWellKnownType reports google.protobuf types, which the marshalers write as their protojson text.
+func IsWellKnownType(md protoreflect.MessageDescriptor) bool {
+	return md.ParentFile().Package() == "google.protobuf"
+}
+
+// parseScalar parses the text of a scalar field the way it is written by scalarText:
+// enums by name or number and bytes in base64.
+func parseScalar(fd protoreflect.FieldDescriptor, text string) (protoreflect.Value, error) {
+	text = strings.TrimSpace(text)
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(text), nil
+	case protoreflect.BytesKind:
+		b, err := base64.StdEncoding.DecodeString(text)
+		return protoreflect.ValueOfBytes(b), err
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(text)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(text)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		n, err := strconv.ParseInt(text, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(text, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(text, 10, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(text, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(text, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(text, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(text, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported field %s", fd.FullName())
+}
+
+// scalarText formats a scalar field value for text encodings.
+func scalarText(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return strconv.Itoa(int(v.Enum()))
+	case protoreflect.FloatKind:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32)
+	case protoreflect.DoubleKind:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
 	}
-	return nil
+	return v.String()
 }
@@ -6,7 +6,16 @@ import (
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 type (
@@ -35,6 +44,29 @@ func (r *Request) Reset()       {}
 func (*Request) String() string { return "Request" }
 func (*Request) ProtoMessage()  {}
 
+// newTestMessage builds the descriptor of msg in the proto3 file test/<name>.proto of package test.
+func newTestMessage(t *testing.T, name string, msg *descriptorpb.DescriptorProto) protoreflect.MessageDescriptor {
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("test/" + name + ".proto"),
+		Package:     proto.String("test"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{msg},
+	}, nil)
+	require.NoError(t, err)
+	return fd.Messages().Get(0)
+}
+
+// testField is an optional field of a test message, with its name as JSON name.
+func testField(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     typ.Enum(),
+	}
+}
+
 func TestFormMarshaler_NewDecoder(t *testing.T) {
 	// Arrange
 	m := &formMarshaler{
@@ -62,3 +94,95 @@ func TestFormMarshaler_NewDecoder(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expected, actual)
 }
+
+// newFormTestMessage builds the descriptor of:
+//
+//	message Payment {
+//	  string id = 1;
+//	  Buyer customer = 2;
+//	  message Buyer { string name = 1; }
+//	}
+func newFormTestMessage(t *testing.T) protoreflect.MessageDescriptor {
+	customer := testField("customer", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	customer.TypeName = proto.String(".test.Payment.Buyer")
+	return newTestMessage(t, "form", &descriptorpb.DescriptorProto{
+		Name:  proto.String("Payment"),
+		Field: []*descriptorpb.FieldDescriptorProto{testField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING), customer},
+		NestedType: []*descriptorpb.DescriptorProto{{
+			Name:  proto.String("Buyer"),
+			Field: []*descriptorpb.FieldDescriptorProto{testField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)},
+		}},
+	})
+}
+
+func TestFormMarshaler_NestedKeys(t *testing.T) {
+	m := &formMarshaler{
+		JSONPb:  &runtime.JSONPb{},
+		aliases: map[string]string{"buyer": "customer.name"},
+	}
+
+	md := newFormTestMessage(t)
+	order := dynamicpb.NewMessage(md)
+	require.NoError(t, m.NewDecoder(strings.NewReader("id=O1&buyer=Tiki")).Decode(order))
+	assert.Equal(t, "O1", order.Get(md.Fields().ByName("id")).String())
+	customer := order.Get(md.Fields().ByName("customer")).Message()
+	assert.Equal(t, "Tiki", customer.Get(customer.Descriptor().Fields().ByName("name")).String())
+
+	order = dynamicpb.NewMessage(md)
+	require.NoError(t, m.NewDecoder(strings.NewReader("customer[name]=Tiki")).Decode(order))
+	customer = order.Get(md.Fields().ByName("customer")).Message()
+	assert.Equal(t, "Tiki", customer.Get(customer.Descriptor().Fields().ByName("name")).String())
+
+	badRequest := &errdetails.BadRequest{}
+	data := "field_violations[1][field]=b&field_violations[0][field]=a&fieldViolations[0].description=required"
+	require.NoError(t, m.NewDecoder(strings.NewReader(data)).Decode(badRequest))
+	assert.True(t, proto.Equal(&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+		{Field: "a", Description: "required"},
+		{Field: "b"},
+	}}, badRequest))
+
+	info := &errdetails.ErrorInfo{}
+	require.NoError(t, m.NewDecoder(strings.NewReader("reason=R&metadata[order_id]=O1&metadata[shop]=S")).Decode(info))
+	assert.True(t, proto.Equal(&errdetails.ErrorInfo{Reason: "R", Metadata: map[string]string{"order_id": "O1", "shop": "S"}}, info))
+
+	help := &errdetails.PreconditionFailure{}
+	assert.Error(t, m.NewDecoder(strings.NewReader("violations[x][type]=T")).Decode(help))
+
+	// indexes are bounded, the list grows up to the index
+	err := m.NewDecoder(strings.NewReader("violations[999999999][type]=T")).Decode(&errdetails.PreconditionFailure{})
+	assert.Contains(t, err.Error(), "invalid index")
+	assert.Contains(t, err.Error(), codes.InvalidArgument.String())
+
+	// keys match fields ignoring case
+	info = &errdetails.ErrorInfo{}
+	require.NoError(t, m.NewDecoder(strings.NewReader("REASON=R&Domain=tiki.vn")).Decode(info))
+	assert.True(t, proto.Equal(&errdetails.ErrorInfo{Reason: "R", Domain: "tiki.vn"}, info))
+
+	// several values for a single field are rejected, like query parameters
+	err = m.NewDecoder(strings.NewReader("reason=A&reason=B")).Decode(&errdetails.ErrorInfo{})
+	assert.Contains(t, err.Error(), "too many values")
+	err = m.NewDecoder(strings.NewReader("buyer=A&buyer=B")).Decode(dynamicpb.NewMessage(md))
+	assert.Contains(t, err.Error(), "too many values")
+
+	// unknown keys are ignored, unknown fields of a known one are not
+	order = dynamicpb.NewMessage(md)
+	require.NoError(t, m.NewDecoder(strings.NewReader("id=O1&unknown=x")).Decode(order))
+	assert.Equal(t, "O1", order.Get(md.Fields().ByName("id")).String())
+	err = m.NewDecoder(strings.NewReader("customer[nmae]=Tiki")).Decode(dynamicpb.NewMessage(md))
+	assert.Contains(t, err.Error(), `unknown field "nmae"`)
+}
+
+func TestFormMarshaler_Marshal(t *testing.T) {
+	m := &formMarshaler{JSONPb: &runtime.JSONPb{}, response: true}
+
+	buf, err := m.Marshal(&Request{RequestID: "r1", Amount: "300000", OrderInfo: "Mua hàng", ErrorCode: 0})
+	require.NoError(t, err)
+	assert.Equal(t, "requestId=r1&amount=300000&orderId=&orderInfo=Mua+h%C3%A0ng&orderType=&transId=&message=&errorCode=0", string(buf))
+	assert.Equal(t, "application/x-www-form-urlencoded", m.ContentType(&Request{}))
+
+	buf, err = m.Marshal(&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "a"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "fieldViolations%5B0%5D%5Bfield%5D=a&fieldViolations%5B0%5D%5Bdescription%5D=", string(buf))
+
+	assert.Equal(t, "application/json", m.ContentType(status.New(codes.Internal, "x").Proto()))
+}
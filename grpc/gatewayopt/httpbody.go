@@ -0,0 +1,237 @@
+package gatewayopt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/protobuf/proto"
+)
+
+// newHTTPBodyMarshaler writes google.api.HttpBody messages as raw bodies and falls back to m for anything else.
+func newHTTPBodyMarshaler(m runtime.Marshaler) *runtime.HTTPBodyMarshaler {
+	return &runtime.HTTPBodyMarshaler{Marshaler: m}
+}
+
+// SetContentDisposition sets the Content-Disposition header of the gateway response,
+// e.g. SetContentDisposition(ctx, "attachment", "report.pdf").
+func SetContentDisposition(ctx context.Context, disposition, filename string) error {
+	v := disposition
+	if filename != "" {
+		v = mime.FormatMediaType(disposition, map[string]string{"filename": filename})
+	}
+	return SetHeader(ctx, "Content-Disposition", v)
+}
+
+// SetCacheControl sets the Cache-Control header of the gateway response.
+func SetCacheControl(ctx context.Context, value string) error {
+	return SetHeader(ctx, "Cache-Control", value)
+}
+
+// SetContentLength announces the total size of a streamed google.api.HttpBody response,
+// which enables Range requests on it.
+func SetContentLength(ctx context.Context, n int64) error {
+	return SetHeader(ctx, "Content-Length", strconv.FormatInt(n, 10))
+}
+
+// HTTPBodyResponse applies the headers set with SetContentDisposition, SetCacheControl and
+// SetContentLength to the gateway response and advertises byte ranges for google.api.HttpBody
+// responses of known length. Use it together with HTTPBodyRangeMiddleware to serve Range requests
+// and to write the chunks of streamed google.api.HttpBody responses back to back.
+func HTTPBodyResponse() runtime.ServeMuxOption {
+	fn := func(ctx context.Context, w http.ResponseWriter, resp proto.Message) error {
+		if md, ok := runtime.ServerMetadataFromContext(ctx); ok {
+			applyHTTPHeaders(w, md.HeaderMD)
+		}
+		if _, ok := resp.(*httpbody.HttpBody); ok {
+			if s, ok := ctx.Value(httpBodyStreamKey{}).(*httpBodyStream); ok {
+				s.dropDelimiter, s.wroteChunk = true, false
+			}
+		}
+
+		h := w.Header()
+		if h.Get("Content-Length") != "" {
+			// an explicit length replaces the chunked encoding set for streams
+			h.Del("Transfer-Encoding")
+			h.Set("Accept-Ranges", "bytes")
+			return nil
+		}
+
+		body, ok := resp.(*httpbody.HttpBody)
+		if ok && h.Get("Transfer-Encoding") == "" {
+			h.Set("Content-Length", strconv.Itoa(len(body.GetData())))
+			h.Set("Accept-Ranges", "bytes")
+		}
+		return nil
+	}
+	return runtime.WithForwardResponseOption(fn)
+}
+
+// HTTPBodyRangeMiddleware serves single byte-range GET requests on responses which
+// advertise "Accept-Ranges: bytes" with a known Content-Length, as set by HTTPBodyResponse.
+// It also drops the delimiter the gateway writes after each chunk of a streamed google.api.HttpBody,
+// other streams keep their delimiters. Other responses are passed through unchanged.
+func HTTPBodyRangeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stream := &httpBodyStream{}
+		r = r.WithContext(context.WithValue(r.Context(), httpBodyStreamKey{}, stream))
+		rw := &rangeResponseWriter{ResponseWriter: w, stream: stream}
+		if r.Method == http.MethodGet {
+			rw.spec = r.Header.Get("Range")
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+type httpBodyStreamKey struct{}
+
+// httpBodyStream tracks the writes of a google.api.HttpBody stream record:
+// runtime.ForwardResponseStream writes its data then the delimiter.
+type httpBodyStream struct {
+	dropDelimiter bool
+	wroteChunk    bool
+}
+
+type rangeResponseWriter struct {
+	http.ResponseWriter
+	stream      *httpBodyStream
+	spec        string
+	wroteHeader bool
+	active      bool
+	discard     bool
+	start, end  int64 // inclusive byte positions of the requested range
+	pos         int64
+}
+
+func (w *rangeResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	h := w.Header()
+	total, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64)
+	if w.spec == "" || code != http.StatusOK || h.Get("Accept-Ranges") != "bytes" || err != nil {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	start, end, err := parseByteRange(w.spec, total)
+	switch err {
+	case nil:
+	case errUnsatisfiableRange:
+		h.Set("Content-Range", fmt.Sprintf("bytes */%d", total))
+		h.Del("Content-Length")
+		w.discard = true
+		w.ResponseWriter.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		return
+	default:
+		// malformed ranges are ignored
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	w.start, w.end, w.active = start, end, true
+	h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, total))
+	h.Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+	w.ResponseWriter.WriteHeader(http.StatusPartialContent)
+}
+
+func (w *rangeResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if s := w.stream; s.dropDelimiter {
+		if s.wroteChunk {
+			s.dropDelimiter = false
+			return len(p), nil
+		}
+		s.wroteChunk = true
+	}
+	if w.discard {
+		return len(p), nil
+	}
+	if !w.active {
+		return w.ResponseWriter.Write(p)
+	}
+
+	n := len(p)
+	from, to := w.start-w.pos, w.end-w.pos+1
+	w.pos += int64(n)
+	if from < 0 {
+		from = 0
+	}
+	if to > int64(n) {
+		to = int64(n)
+	}
+	if from >= to {
+		return n, nil
+	}
+	if _, err := w.ResponseWriter.Write(p[from:to]); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (w *rangeResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+var (
+	errInvalidRange       = errors.New("invalid range")
+	errUnsatisfiableRange = errors.New("unsatisfiable range")
+)
+
+// parseByteRange parses a single "bytes=" range against a resource of the given size.
+func parseByteRange(spec string, size int64) (start, end int64, err error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(spec, prefix) || strings.Contains(spec, ",") {
+		return 0, 0, errInvalidRange
+	}
+	i := strings.Index(spec, "-")
+	if i < 0 {
+		return 0, 0, errInvalidRange
+	}
+	first, last := strings.TrimSpace(spec[len(prefix):i]), strings.TrimSpace(spec[i+1:])
+
+	if first == "" {
+		// suffix range, the last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, errInvalidRange
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, errUnsatisfiableRange
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, errInvalidRange
+	}
+	end = size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return 0, 0, errInvalidRange
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return 0, 0, errUnsatisfiableRange
+	}
+	return start, end, nil
+}
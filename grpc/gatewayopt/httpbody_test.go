@@ -0,0 +1,103 @@
+package gatewayopt
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func newHTTPBodyTestHandler(stream bool) http.Handler {
+	mux := runtime.NewServeMux(ProtoJSONMarshaler(), HTTPBodyResponse())
+	chunks := []string{"hello ", "range ", "world"}
+
+	_ = mux.HandlePath(http.MethodGet, "/download", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{
+			HeaderMD: metadata.Pairs(
+				httpHeaderMetadataPrefix+"content-disposition", `attachment; filename="hello.txt"`,
+				httpHeaderMetadataPrefix+"content-length", "17",
+			),
+		})
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+
+		if !stream {
+			runtime.ForwardResponseMessage(ctx, mux, outbound, w, r,
+				&httpbody.HttpBody{ContentType: "text/plain", Data: []byte("hello range world")},
+				mux.GetForwardResponseOptions()...)
+			return
+		}
+
+		i := 0
+		recv := func() (proto.Message, error) {
+			if i == len(chunks) {
+				return nil, io.EOF
+			}
+			i++
+			return &httpbody.HttpBody{ContentType: "text/plain", Data: []byte(chunks[i-1])}, nil
+		}
+		runtime.ForwardResponseStream(ctx, mux, outbound, w, r, recv, mux.GetForwardResponseOptions()...)
+	})
+
+	return HTTPBodyRangeMiddleware(mux)
+}
+
+func TestHTTPBodyResponse(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		h := newHTTPBodyTestHandler(stream)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/download", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "hello range world", w.Body.String())
+		assert.Equal(t, "text/plain", w.Result().Header.Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="hello.txt"`, w.Result().Header.Get("Content-Disposition"))
+		assert.Empty(t, w.Result().Header.Get("Grpc-Metadata-X-Http-Header-Content-Disposition"))
+		assert.Equal(t, "bytes", w.Result().Header.Get("Accept-Ranges"))
+
+		w = httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/download", nil)
+		r.Header.Set("Range", "bytes=4-12")
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "o range w", w.Body.String())
+		assert.Equal(t, "bytes 4-12/17", w.Result().Header.Get("Content-Range"))
+		assert.Equal(t, "9", w.Result().Header.Get("Content-Length"))
+
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest(http.MethodGet, "/download", nil)
+		r.Header.Set("Range", "bytes=20-")
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+		assert.Equal(t, "bytes */17", w.Result().Header.Get("Content-Range"))
+	}
+}
+
+func TestHTTPBodyRangeMiddleware_streamDelimiter(t *testing.T) {
+	mux := runtime.NewServeMux(ProtoJSONMarshaler(), HTTPBodyResponse())
+	_ = mux.HandlePath(http.MethodGet, "/events", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{})
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		events := []string{"created", "paid"}
+		recv := func() (proto.Message, error) {
+			if len(events) == 0 {
+				return nil, io.EOF
+			}
+			ev := wrapperspb.String(events[0])
+			events = events[1:]
+			return ev, nil
+		}
+		runtime.ForwardResponseStream(ctx, mux, outbound, w, r, recv, mux.GetForwardResponseOptions()...)
+	})
+
+	w := httptest.NewRecorder()
+	HTTPBodyRangeMiddleware(mux).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"result\":\"created\"}\n{\"result\":\"paid\"}\n", w.Body.String())
+}
@@ -0,0 +1,407 @@
+package gatewayopt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	defaultMultipartMaxMemory  = 10 << 20
+	defaultMultipartMaxRequest = 32 << 20
+)
+
+type multipartConfig struct {
+	maxMemory      int64
+	maxFileSize    int64
+	maxRequestSize int64
+}
+
+// MultipartOption configures MultipartFormDataMarshaler.
+type MultipartOption func(*multipartConfig)
+
+// WithMultipartMaxMemory sets the number of bytes of file parts kept in memory, larger files are written
+// to temporary files like multipart.Reader.ReadForm does. Form values get 10MB on top of it. Default is 10MB.
+func WithMultipartMaxMemory(n int64) MultipartOption {
+	return func(c *multipartConfig) {
+		c.maxMemory = n
+	}
+}
+
+// WithMultipartMaxFileSize limits the size of a single file part, 0 means no limit.
+func WithMultipartMaxFileSize(n int64) MultipartOption {
+	return func(c *multipartConfig) {
+		c.maxFileSize = n
+	}
+}
+
+// WithMultipartMaxRequestSize limits the size of the whole multipart body. Default is 32MB.
+func WithMultipartMaxRequestSize(n int64) MultipartOption {
+	return func(c *multipartConfig) {
+		c.maxRequestSize = n
+	}
+}
+
+// MultipartFormDataMarshaler is custom Marshaler that supports reading multipart/form-data mime type.
+// Form values populate scalar fields the same way as query parameters.
+// File parts populate the field named by the part, which must be either a bytes field or
+// a FileUpload-like message having `filename`, `content_type` and `data` fields:
+//
+//	message FileUpload {
+//	  string filename = 1;
+//	  string content_type = 2;
+//	  bytes data = 3;
+//	}
+//
+// Both may be repeated to accept several files under the same name.
+// Files beyond the memory limit are written to temporary files, removed once the response is written.
+// A FileUpload-like message may declare a `string path` field to get the name of that file instead of its data,
+// other fields get the data read back in memory.
+// Responses are marshaled as JSON. The gateway server must be wrapped with MultipartMiddleware,
+// server.WithMultipartForm installs both.
+func MultipartFormDataMarshaler(opts ...MultipartOption) runtime.ServeMuxOption {
+	c := &multipartConfig{
+		maxMemory:      defaultMultipartMaxMemory,
+		maxRequestSize: defaultMultipartMaxRequest,
+	}
+	for _, f := range opts {
+		f(c)
+	}
+
+	return runtime.WithMarshalerOption("multipart/form-data", &multipartMarshaler{
+		JSONPb: &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: true},
+		},
+		config: c,
+	})
+}
+
+type multipartMarshaler struct {
+	*runtime.JSONPb
+	config *multipartConfig
+}
+
+func (m *multipartMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
+	return runtime.DecoderFunc(func(v interface{}) error {
+		if err := m.decode(r, v); err != nil {
+			if _, ok := status.FromError(err); ok {
+				return err
+			}
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil
+	})
+}
+
+func (m *multipartMarshaler) decode(r io.Reader, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("not proto message")
+	}
+
+	var limited *limitedReader
+	if m.config.maxRequestSize > 0 {
+		limited = &limitedReader{r: r, n: m.config.maxRequestSize}
+		r = limited
+	}
+
+	err := m.decodeParts(bufio.NewReader(r), msg)
+	if limited != nil && limited.exceeded {
+		return errRequestTooLarge
+	}
+	return err
+}
+
+func (m *multipartMarshaler) decodeParts(br *bufio.Reader, msg proto.Message) error {
+	boundary, dir, err := multipartHeader(br)
+	if err != nil {
+		return err
+	}
+
+	values := url.Values{}
+	var files []filePart
+	// form values get 10MB on top of maxMemory, like multipart.Reader.ReadForm
+	maxValueBytes := m.config.maxMemory + 10<<20
+	maxFileBytes := m.config.maxMemory
+	mr := multipart.NewReader(br, boundary)
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid multipart form: %w", err)
+		}
+		name := p.FormName()
+		if name == "" {
+			continue
+		}
+
+		if p.FileName() == "" {
+			data, err := readPart(p, maxValueBytes)
+			if err == errPartTooLarge {
+				return fmt.Errorf("form values exceed %d bytes", maxValueBytes)
+			}
+			if err != nil {
+				return err
+			}
+			maxValueBytes -= int64(len(data))
+			values.Add(name, string(data))
+			continue
+		}
+
+		f, err := m.readFile(p, dir, &maxFileBytes)
+		if err == errPartTooLarge {
+			return fmt.Errorf("file %q exceeds %d bytes", p.FileName(), m.config.maxFileSize)
+		}
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+
+	if err := runtime.PopulateQueryParameters(msg, values, &utilities.DoubleArray{}); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := setFilePart(msg.ProtoReflect(), strings.Split(f.name, "."), f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// multipartHeader reads the boundary and the temporary directory MultipartMiddleware puts
+// on the first two lines of the body.
+func multipartHeader(br *bufio.Reader) (boundary, dir string, err error) {
+	if boundary, err = br.ReadString('\n'); err != nil || boundary == "\n" {
+		return "", "", fmt.Errorf("invalid multipart form: missing boundary")
+	}
+	if dir, err = br.ReadString('\n'); err != nil {
+		return "", "", fmt.Errorf("invalid multipart form: missing boundary")
+	}
+	return strings.TrimSuffix(boundary, "\n"), strings.TrimSuffix(dir, "\n"), nil
+}
+
+// MultipartMiddleware passes the boundary of multipart/form-data requests, from their Content-Type header,
+// to MultipartFormDataMarshaler which only sees the body, along with a temporary directory for the files
+// too large to be kept in memory. The directory is removed once the response is written.
+func MultipartMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mt, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mt != "multipart/form-data" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// files are kept in memory when no directory can be created
+		dir, err := ioutil.TempDir("", "multipart-")
+		if err == nil {
+			defer os.RemoveAll(dir)
+		}
+		// a missing boundary is reported by the marshaler, through the gateway error handler
+		r.Body = multipartBody{
+			Reader: io.MultiReader(strings.NewReader(params["boundary"]+"\n"+dir+"\n"), r.Body),
+			Closer: r.Body,
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type multipartBody struct {
+	io.Reader
+	io.Closer
+}
+
+// filePart is a file of the form, read in memory or written to the temporary file at path.
+type filePart struct {
+	name        string
+	filename    string
+	contentType string
+	data        []byte
+	path        string
+}
+
+// readFile reads the file part p in memory while it fits in the memory left,
+// then writes it to a temporary file of dir. Without dir the file is kept in memory.
+func (m *multipartMarshaler) readFile(p *multipart.Part, dir string, memory *int64) (filePart, error) {
+	f := filePart{
+		name:        p.FormName(),
+		filename:    p.FileName(),
+		contentType: p.Header.Get("Content-Type"),
+	}
+	var r io.Reader = p
+	if m.config.maxFileSize > 0 {
+		r = io.LimitReader(p, m.config.maxFileSize+1)
+	}
+
+	var buf bytes.Buffer
+	size, err := buf.ReadFrom(io.LimitReader(r, *memory+1))
+	if err != nil {
+		return f, err
+	}
+	if size > *memory && dir != "" {
+		tmp, err := ioutil.TempFile(dir, "file-")
+		if err != nil {
+			return f, err
+		}
+		size, err = io.Copy(tmp, io.MultiReader(&buf, r))
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return f, err
+		}
+		f.path = tmp.Name()
+	} else {
+		n, err := buf.ReadFrom(r)
+		if err != nil {
+			return f, err
+		}
+		size += n
+		f.data = buf.Bytes()
+		if *memory -= size; *memory < 0 {
+			*memory = 0
+		}
+	}
+
+	if m.config.maxFileSize > 0 && size > m.config.maxFileSize {
+		return f, errPartTooLarge
+	}
+	return f, nil
+}
+
+// bytes returns the data of f, reading its temporary file back when it has one.
+func (f filePart) bytes() ([]byte, error) {
+	if f.path == "" {
+		return f.data, nil
+	}
+	return ioutil.ReadFile(f.path)
+}
+
+var errPartTooLarge = errors.New("multipart part too large")
+
+// readPart reads at most limit bytes of p, failing with errPartTooLarge as soon as there are more.
+// 0 means no limit.
+func readPart(p io.Reader, limit int64) ([]byte, error) {
+	var buf bytes.Buffer
+	if limit <= 0 {
+		_, err := buf.ReadFrom(p)
+		return buf.Bytes(), err
+	}
+	if _, err := buf.ReadFrom(io.LimitReader(p, limit+1)); err != nil {
+		return nil, err
+	}
+	if int64(buf.Len()) > limit {
+		return nil, errPartTooLarge
+	}
+	return buf.Bytes(), nil
+}
+
+func setFilePart(msg protoreflect.Message, path []string, f filePart) error {
+	fd := fieldByName(msg.Descriptor(), path[0])
+	if fd == nil {
+		// unknown parts are ignored like unknown query parameters
+		return nil
+	}
+	if len(path) > 1 {
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return fmt.Errorf("invalid path %q for file %q", strings.Join(path, "."), f.filename)
+		}
+		return setFilePart(msg.Mutable(fd).Message(), path[1:], f)
+	}
+
+	var val protoreflect.Value
+	switch {
+	case fd.Kind() == protoreflect.BytesKind:
+		data, err := f.bytes()
+		if err != nil {
+			return err
+		}
+		val = protoreflect.ValueOfBytes(data)
+	case fd.Kind() == protoreflect.MessageKind && isFileUpload(fd.Message()):
+		var upload protoreflect.Message
+		if fd.IsList() {
+			upload = msg.Mutable(fd).List().NewElement().Message()
+		} else {
+			upload = msg.NewField(fd).Message()
+		}
+		fields := fd.Message().Fields()
+		upload.Set(fields.ByName("filename"), protoreflect.ValueOfString(f.filename))
+		upload.Set(fields.ByName("content_type"), protoreflect.ValueOfString(f.contentType))
+		if path := fields.ByName("path"); f.path != "" && path != nil && path.Kind() == protoreflect.StringKind && !path.IsList() {
+			upload.Set(path, protoreflect.ValueOfString(f.path))
+		} else {
+			data, err := f.bytes()
+			if err != nil {
+				return err
+			}
+			upload.Set(fields.ByName("data"), protoreflect.ValueOfBytes(data))
+		}
+		val = protoreflect.ValueOfMessage(upload)
+	default:
+		return fmt.Errorf("field %q cannot hold file %q", fd.FullName(), f.filename)
+	}
+
+	if fd.IsList() {
+		msg.Mutable(fd).List().Append(val)
+		return nil
+	}
+	msg.Set(fd, val)
+	return nil
+}
+
+func isFileUpload(md protoreflect.MessageDescriptor) bool {
+	fields := md.Fields()
+	filename, contentType, data := fields.ByName("filename"), fields.ByName("content_type"), fields.ByName("data")
+	return filename != nil && filename.Kind() == protoreflect.StringKind && !filename.IsList() &&
+		contentType != nil && contentType.Kind() == protoreflect.StringKind && !contentType.IsList() &&
+		data != nil && data.Kind() == protoreflect.BytesKind && !data.IsList()
+}
+
+// fieldByName looks a field up by its json name first, then by its proto name.
+func fieldByName(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	if fd := md.Fields().ByJSONName(name); fd != nil {
+		return fd
+	}
+	return md.Fields().ByName(protoreflect.Name(name))
+}
+
+var errRequestTooLarge = errors.New("request body too large")
+
+// limitedReader fails with errRequestTooLarge instead of returning EOF once more than n bytes are read.
+type limitedReader struct {
+	r        io.Reader
+	n        int64
+	exceeded bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.n {
+		l.exceeded = true
+		return int(l.n), errRequestTooLarge
+	}
+	l.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,150 @@
+package gatewayopt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// newMultipartBody returns the body the marshaler reads once MultipartMiddleware handled the request.
+func newMultipartBody(t *testing.T, contentType string, data []byte) io.Reader {
+	body := &bytes.Buffer{}
+	body.WriteString("preamble\r\n")
+	w := multipart.NewWriter(body)
+	require.NoError(t, w.WriteField("content_type", contentType))
+	fw, err := w.CreateFormFile("data", "report.csv")
+	require.NoError(t, err)
+	_, err = fw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return multipartMiddlewareBody(t, w.FormDataContentType(), body)
+}
+
+func multipartMiddlewareBody(t *testing.T, contentType string, body io.Reader) io.Reader {
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	r.Header.Set("Content-Type", contentType)
+	var got []byte
+	MultipartMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		got, err = ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	return bytes.NewReader(got)
+}
+
+func TestMultipartMarshaler_NewDecoder(t *testing.T) {
+	m := &multipartMarshaler{config: &multipartConfig{maxMemory: 1 << 10, maxFileSize: 16}}
+
+	t.Run("file part", func(t *testing.T) {
+		actual := &httpbody.HttpBody{}
+		err := m.NewDecoder(newMultipartBody(t, "text/csv", []byte("a,b\n1,2\n"))).Decode(actual)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "text/csv", actual.ContentType)
+		assert.Equal(t, []byte("a,b\n1,2\n"), actual.Data)
+	})
+
+	t.Run("file too large", func(t *testing.T) {
+		err := m.NewDecoder(newMultipartBody(t, "text/csv", []byte(strings.Repeat("a", 17)))).Decode(&httpbody.HttpBody{})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("request too large", func(t *testing.T) {
+		m := &multipartMarshaler{config: &multipartConfig{maxMemory: 1 << 10, maxRequestSize: 64}}
+		err := m.NewDecoder(newMultipartBody(t, "text/csv", []byte("a,b\n1,2\n"))).Decode(&httpbody.HttpBody{})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, err.Error(), errRequestTooLarge.Error())
+	})
+
+	t.Run("file too large is rejected before the rest is read", func(t *testing.T) {
+		body := multipartMiddlewareBody(t, "multipart/form-data; boundary=b", io.MultiReader(
+			strings.NewReader("--b\r\nContent-Disposition: form-data; name=\"data\"; filename=\"report.csv\"\r\n\r\n"),
+			strings.NewReader(strings.Repeat("a", 4096)),
+		))
+		err := m.NewDecoder(io.MultiReader(body, iotest.ErrReader(errors.New("read past the limit")))).Decode(&httpbody.HttpBody{})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, err.Error(), "exceeds 16 bytes")
+	})
+
+	t.Run("missing boundary", func(t *testing.T) {
+		body := multipartMiddlewareBody(t, "multipart/form-data", strings.NewReader("--x\r\n"))
+		err := m.NewDecoder(body).Decode(&httpbody.HttpBody{})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, err.Error(), "missing boundary")
+	})
+}
+
+func TestMultipartMarshaler_spill(t *testing.T) {
+	m := &multipartMarshaler{config: &multipartConfig{maxMemory: 4}}
+	file := testField("file", 1, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	file.TypeName = proto.String(".test.Form.Upload")
+	form := newTestMessage(t, "upload", &descriptorpb.DescriptorProto{
+		Name:  proto.String("Form"),
+		Field: []*descriptorpb.FieldDescriptorProto{file},
+		NestedType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Upload"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				testField("filename", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				testField("content_type", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				testField("data", 3, descriptorpb.FieldDescriptorProto_TYPE_BYTES),
+				testField("path", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			},
+		}},
+	})
+	upload := form.Messages().Get(0)
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for _, name := range []string{"data", "file"} {
+		fw, err := w.CreateFormFile(name, "report.csv")
+		require.NoError(t, err)
+		_, err = fw.Write([]byte("a,b\n1,2\n"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body.Bytes()))
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	var path string
+	MultipartMiddleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		data, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		actual := &httpbody.HttpBody{}
+		require.NoError(t, m.NewDecoder(bytes.NewReader(data)).Decode(actual))
+		assert.Equal(t, []byte("a,b\n1,2\n"), actual.Data)
+
+		msg := dynamicpb.NewMessage(form)
+		require.NoError(t, m.NewDecoder(bytes.NewReader(data)).Decode(msg))
+		msg = msg.Get(form.Fields().ByName("file")).Message().Interface().(*dynamicpb.Message)
+		path = msg.Get(upload.Fields().ByName("path")).String()
+		assert.Empty(t, msg.Get(upload.Fields().ByName("data")).Bytes())
+		spilled, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("a,b\n1,2\n"), spilled)
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "the temporary file is removed after the response")
+}
@@ -0,0 +1,149 @@
+package gatewayopt
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// MarshalerOffer is a response encoding available for content negotiation.
+type MarshalerOffer struct {
+	MIME      string
+	Marshaler runtime.Marshaler
+}
+
+// DefaultMarshalerOffers returns the JSON, protobuf binary and form-urlencoded encodings, in that order of preference.
+func DefaultMarshalerOffers() []MarshalerOffer {
+	return []MarshalerOffer{
+		{MIME: "application/json", Marshaler: newHTTPBodyMarshaler(&runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: true},
+		})},
+		{MIME: "application/x-protobuf", Marshaler: &runtime.ProtoMarshaller{}},
+		{MIME: formContentType, Marshaler: &formMarshaler{
+			JSONPb: &runtime.JSONPb{
+				MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: true},
+			},
+			response: true,
+		}},
+	}
+}
+
+// NegotiatedMarshalers registers the offered marshalers by MIME type.
+// Use it together with ContentNegotiationMiddleware so the response marshaler is chosen from the Accept header.
+func NegotiatedMarshalers(offers ...MarshalerOffer) runtime.ServeMuxOption {
+	return func(mux *runtime.ServeMux) {
+		for _, o := range offers {
+			runtime.WithMarshalerOption(o.MIME, o.Marshaler)(mux)
+		}
+	}
+}
+
+// ContentNegotiationMiddleware picks the best of the offered MIME types for the Accept header, honoring
+// q-values and wildcards, and rewrites the header to it so grpc-gateway selects the matching marshaler.
+// Ties are broken by the order of offers. Requests accepting none of them get 406 Not Acceptable
+// listing the supported types, written by the error handler of mux. Responses always carry "Vary: Accept".
+func ContentNegotiationMiddleware(mux *runtime.ServeMux, offers ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+
+			accept := strings.Join(r.Header.Values("Accept"), ",")
+			if strings.TrimSpace(accept) == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			chosen, ok := NegotiateContentType(accept, offers)
+			if !ok {
+				_, outbound := runtime.MarshalerForRequest(mux, r)
+				runtime.HTTPError(r.Context(), mux, outbound, w, r, &runtime.HTTPStatusError{
+					HTTPStatus: http.StatusNotAcceptable,
+					Err:        status.Error(codes.InvalidArgument, "supported media types: "+strings.Join(offers, ", ")),
+				})
+				return
+			}
+
+			r.Header.Set("Accept", chosen)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// NegotiateContentType returns the offer with the highest quality in the Accept header value.
+func NegotiateContentType(accept string, offers []string) (string, bool) {
+	ranges := parseAccept(accept)
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		typ, subtype := splitMediaType(offer)
+
+		q, specificity := 0.0, -1
+		for _, mr := range ranges {
+			s := -1
+			switch {
+			case mr.typ == typ && mr.subtype == subtype:
+				s = 2
+			case mr.typ == typ && mr.subtype == "*":
+				s = 1
+			case mr.typ == "*" && mr.subtype == "*":
+				s = 0
+			}
+			// the most specific range decides the quality of an offer
+			if s > specificity {
+				q, specificity = mr.q, s
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		mt, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			// ParseMediaType rejects a bare "*"
+			if !strings.HasPrefix(part, "*") {
+				continue
+			}
+			mt = "*/*"
+		}
+
+		mr := mediaRange{q: 1}
+		mr.typ, mr.subtype = splitMediaType(mt)
+		if v, ok := params["q"]; ok {
+			if q, err := strconv.ParseFloat(v, 64); err == nil && q >= 0 && q <= 1 {
+				mr.q = q
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+	return ranges
+}
+
+func splitMediaType(mt string) (string, string) {
+	mt = strings.ToLower(strings.TrimSpace(mt))
+	if i := strings.IndexByte(mt, '/'); i >= 0 {
+		return mt[:i], mt[i+1:]
+	}
+	return mt, "*"
+}
@@ -0,0 +1,65 @@
+package gatewayopt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{"application/json", "application/x-protobuf", "application/xml"}
+	tests := []struct {
+		accept string
+		want   string
+		ok     bool
+	}{
+		{"application/json", "application/json", true},
+		{"*/*", "application/json", true},
+		{"application/x-protobuf;q=0.9, application/json;q=0.5", "application/x-protobuf", true},
+		{"application/*;q=0.2, application/xml", "application/xml", true},
+		{"*/*;q=0.1, application/json;q=0", "application/x-protobuf", true},
+		{"text/html", "", false},
+		{"application/json;q=0", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := NegotiateContentType(tt.accept, offers)
+		assert.Equal(t, tt.ok, ok, tt.accept)
+		assert.Equal(t, tt.want, got, tt.accept)
+	}
+}
+
+func TestContentNegotiationMiddleware(t *testing.T) {
+	offers := DefaultMarshalerOffers()
+	var handled error
+	mux := runtime.NewServeMux(ProtoJSONMarshaler(), NegotiatedMarshalers(offers...),
+		runtime.WithErrorHandler(func(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+			handled = err
+			runtime.DefaultHTTPErrorHandler(ctx, mux, m, w, r, err)
+		}))
+
+	var outbound runtime.Marshaler
+	h := ContentNegotiationMiddleware(mux, "application/json", "application/x-protobuf")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, outbound = runtime.MarshalerForRequest(mux, r)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/html;q=0.9, application/x-protobuf;q=0.8, */*;q=0.1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	assert.IsType(t, &runtime.ProtoMarshaller{}, outbound)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/html")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	assert.Contains(t, w.Body.String(), "application/json, application/x-protobuf")
+	assert.Error(t, handled, "406 goes through the error handler of the mux")
+}
@@ -0,0 +1,155 @@
+package gatewayopt
+
+import (
+	"context"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// httpHeaderMetadataPrefix marks gRPC header metadata which is written as a plain HTTP response header.
+	httpHeaderMetadataPrefix = "x-http-header-"
+	// httpStatusMetadataKey carries the HTTP status code of the gateway response.
+	httpStatusMetadataKey = "x-http-status"
+)
+
+// SetHeader sets an HTTP header of the gateway response from a gRPC handler,
+// calling it several times with the same key adds values.
+func SetHeader(ctx context.Context, key, value string) error {
+	return grpc.SetHeader(ctx, metadata.Pairs(httpHeaderMetadataPrefix+strings.ToLower(key), value))
+}
+
+// SetCookie adds a Set-Cookie header to the gateway response from a gRPC handler.
+func SetCookie(ctx context.Context, cookie *http.Cookie) error {
+	return SetHeader(ctx, "Set-Cookie", cookie.String())
+}
+
+// SetHTTPStatus sets the HTTP status code of a successful gateway response from a gRPC handler.
+func SetHTTPStatus(ctx context.Context, code int) error {
+	return grpc.SetHeader(ctx, metadata.Pairs(httpStatusMetadataKey, strconv.Itoa(code)))
+}
+
+// SetRedirect redirects the gateway response to url with a 3xx code, 301 when code is not a redirection.
+func SetRedirect(ctx context.Context, url string, code int) error {
+	if code < 300 || code > 399 {
+		code = http.StatusMovedPermanently
+	}
+	if err := SetHeader(ctx, "Location", url); err != nil {
+		return err
+	}
+	return SetHTTPStatus(ctx, code)
+}
+
+type responseControlKey struct{}
+
+// responseControlWriter writes the status recorded by ResponseControl in place of the 200 of the gateway.
+type responseControlWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *responseControlWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if code == http.StatusOK && w.status != 0 {
+		code = w.status
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseControlWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseControlWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// ResponseControlMiddleware lets ResponseControl set the status code of gateway responses,
+// which is written along with the response instead of from the forward response option.
+func ResponseControlMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseControlWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), responseControlKey{}, rw)))
+	})
+}
+
+// ResponseControl applies the status code, headers and cookies set with SetHTTPStatus, SetHeader,
+// SetCookie and SetRedirect to the gateway response. The status code needs ResponseControlMiddleware.
+// The metadata is consumed when applied, so it is written once and never forwarded as Grpc-Metadata- headers.
+func ResponseControl() runtime.ServeMuxOption {
+	fn := func(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+		md, ok := runtime.ServerMetadataFromContext(ctx)
+		if !ok {
+			return nil
+		}
+
+		applyHTTPHeaders(w, md.HeaderMD)
+
+		vs := md.HeaderMD.Get(httpStatusMetadataKey)
+		if len(vs) == 0 {
+			return nil
+		}
+		md.HeaderMD.Delete(httpStatusMetadataKey)
+		w.Header().Del(runtime.MetadataHeaderPrefix + httpStatusMetadataKey)
+
+		code, err := strconv.Atoi(vs[len(vs)-1])
+		if err != nil || code < 100 || code > 599 {
+			grpclog.Infof("Invalid HTTP status in server metadata: %v", vs)
+			return nil
+		}
+		rw, ok := ctx.Value(responseControlKey{}).(*responseControlWriter)
+		if !ok {
+			grpclog.Infof("HTTP status %d not applied without ResponseControlMiddleware", code)
+			return nil
+		}
+		rw.status = code
+		return nil
+	}
+	return runtime.WithForwardResponseOption(fn)
+}
+
+// ApplyErrorResponseControl applies the headers and cookies set with SetHeader and SetCookie to an error
+// response and drops the status code, which is for successful responses. Error handlers call it before
+// forwarding the header metadata, so that the control metadata is never sent as Grpc-Metadata- headers.
+func ApplyErrorResponseControl(ctx context.Context, w http.ResponseWriter) {
+	md, ok := runtime.ServerMetadataFromContext(ctx)
+	if !ok {
+		return
+	}
+	applyHTTPHeaders(w, md.HeaderMD)
+	md.HeaderMD.Delete(httpStatusMetadataKey)
+}
+
+// applyHTTPHeaders writes header metadata carrying httpHeaderMetadataPrefix as HTTP headers,
+// removes their Grpc-Metadata- forwarded copies and consumes them from md.
+func applyHTTPHeaders(w http.ResponseWriter, md metadata.MD) {
+	for k, vs := range md {
+		if !strings.HasPrefix(k, httpHeaderMetadataPrefix) {
+			continue
+		}
+		w.Header().Del(runtime.MetadataHeaderPrefix + k)
+		key := textproto.CanonicalMIMEHeaderKey(strings.TrimPrefix(k, httpHeaderMetadataPrefix))
+		w.Header().Del(key)
+		for _, v := range vs {
+			w.Header().Add(key, v)
+		}
+		delete(md, k)
+	}
+}
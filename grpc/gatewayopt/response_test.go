@@ -0,0 +1,94 @@
+package gatewayopt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// headerStream records header metadata set by a handler through grpc.SetHeader.
+type headerStream struct {
+	grpc.ServerTransportStream
+	md metadata.MD
+}
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.md = metadata.Join(s.md, md)
+	return nil
+}
+
+func TestResponseControl(t *testing.T) {
+	stream := &headerStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	require.NoError(t, SetHTTPStatus(ctx, http.StatusCreated))
+	require.NoError(t, SetHeader(ctx, "X-Request-Id", "abc"))
+	require.NoError(t, SetCookie(ctx, &http.Cookie{Name: "session", Value: "s1"}))
+	require.NoError(t, SetCookie(ctx, &http.Cookie{Name: "theme", Value: "dark"}))
+
+	res := forwardControlledResponse(stream.md)
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Equal(t, "abc", res.Header.Get("X-Request-Id"))
+	assert.Equal(t, []string{"session=s1", "theme=dark"}, res.Header.Values("Set-Cookie"))
+	for k := range res.Header {
+		assert.NotContains(t, k, runtime.MetadataHeaderPrefix)
+	}
+	assert.Empty(t, stream.md.Get(httpStatusMetadataKey))
+}
+
+// forwardControlledResponse forwards an empty response with the header metadata through ResponseControl.
+func forwardControlledResponse(md metadata.MD) *http.Response {
+	mux := runtime.NewServeMux(ProtoJSONMarshaler(), ResponseControl())
+	w := httptest.NewRecorder()
+	ResponseControlMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{HeaderMD: md})
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, &emptypb.Empty{}, mux.GetForwardResponseOptions()...)
+	})).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	return w.Result()
+}
+
+func TestSetRedirect(t *testing.T) {
+	tests := []struct {
+		name string
+		code int
+		want int
+	}{
+		{"found", http.StatusFound, http.StatusFound},
+		{"permanent", http.StatusPermanentRedirect, http.StatusPermanentRedirect},
+		{"not a redirection", http.StatusOK, http.StatusMovedPermanently},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &headerStream{}
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+			require.NoError(t, SetRedirect(ctx, "https://tiki.vn/checkout", tt.code))
+
+			res := forwardControlledResponse(stream.md)
+
+			assert.Equal(t, tt.want, res.StatusCode)
+			assert.Equal(t, "https://tiki.vn/checkout", res.Header.Get("Location"))
+		})
+	}
+}
+
+func TestApplyErrorResponseControl(t *testing.T) {
+	stream := &headerStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	require.NoError(t, SetHTTPStatus(ctx, http.StatusCreated))
+	require.NoError(t, SetHeader(ctx, "Deprecation", "true"))
+	stream.md.Set("x-request-id", "abc")
+
+	w := httptest.NewRecorder()
+	ApplyErrorResponseControl(runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{HeaderMD: stream.md}), w)
+
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Equal(t, metadata.Pairs("x-request-id", "abc"), stream.md, "only the control metadata is consumed")
+}
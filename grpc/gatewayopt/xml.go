@@ -0,0 +1,408 @@
+package gatewayopt
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/tikivn/tikit-go-kit/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// XMLMarshaler is custom Marshaler that supports reading and writing application/xml and text/xml mime types.
+// A message is an element named after the (pb.xml_root) message option or the message name,
+// its fields are child elements named after the (pb.xml_name) field option or the json_name.
+// Fields having the (pb.xml_attr) option are rendered as attributes, repeated fields as
+// repeated elements and map entries as elements with a key attribute:
+//
+//	message Callback {
+//	  option (pb.xml_root) = "callback";
+//	  string id = 1 [(pb.xml_attr) = true];
+//	  repeated Item items = 2 [(pb.xml_name) = "item"];
+//	}
+//
+//	<callback id="1"><item>...</item><item>...</item></callback>
+//
+// Stream records are written as newline delimited <result> or <error> elements without an XML declaration.
+func XMLMarshaler() runtime.ServeMuxOption {
+	return func(mux *runtime.ServeMux) {
+		runtime.WithMarshalerOption("application/xml", &xmlMarshaler{contentType: "application/xml"})(mux)
+		runtime.WithMarshalerOption("text/xml", &xmlMarshaler{contentType: "text/xml"})(mux)
+	}
+}
+
+type xmlMarshaler struct {
+	contentType string
+}
+
+var _ runtime.Marshaler = (*xmlMarshaler)(nil)
+
+func (m *xmlMarshaler) ContentType(_ interface{}) string {
+	return m.contentType
+}
+
+func (m *xmlMarshaler) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	e := xml.NewEncoder(&buf)
+	if !isStreamRecord(v) {
+		buf.WriteString(xml.Header)
+	}
+	if err := encodeXMLValue(e, v); err != nil {
+		return nil, err
+	}
+	if err := e.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (m *xmlMarshaler) Unmarshal(data []byte, v interface{}) error {
+	return m.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (m *xmlMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
+	d := xml.NewDecoder(r)
+	return runtime.DecoderFunc(func(v interface{}) error {
+		msg, ok := v.(proto.Message)
+		if !ok {
+			return fmt.Errorf("not proto message")
+		}
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return err
+			}
+			if start, ok := tok.(xml.StartElement); ok {
+				return decodeXMLMessage(d, start, msg.ProtoReflect())
+			}
+		}
+	})
+}
+
+// NewEncoder writes the XML declaration once, before the first value.
+func (m *xmlMarshaler) NewEncoder(w io.Writer) runtime.Encoder {
+	e := xml.NewEncoder(w)
+	var wroteHeader bool
+	return runtime.EncoderFunc(func(v interface{}) error {
+		if !wroteHeader {
+			if _, err := io.WriteString(w, xml.Header); err != nil {
+				return err
+			}
+			wroteHeader = true
+		}
+		if err := encodeXMLValue(e, v); err != nil {
+			return err
+		}
+		return e.Flush()
+	})
+}
+
+// isStreamRecord reports whether v is a record or an error chunk written by runtime.ForwardResponseStream.
+func isStreamRecord(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, map[string]proto.Message:
+		return true
+	}
+	return false
+}
+
+func encodeXMLValue(e *xml.Encoder, v interface{}) error {
+	switch v := v.(type) {
+	case proto.Message:
+		msg := v.ProtoReflect()
+		return encodeXMLMessage(e, xml.StartElement{Name: xml.Name{Local: xmlRootName(msg.Descriptor())}}, msg)
+	case map[string]interface{}:
+		// stream records produced by runtime.ForwardResponseStream
+		for _, k := range sortedKeys(v) {
+			if err := encodeXMLNamed(e, k, v[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]proto.Message:
+		for k, msg := range v {
+			if err := encodeXMLMessage(e, xml.StartElement{Name: xml.Name{Local: k}}, msg.ProtoReflect()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return e.Encode(v)
+}
+
+func encodeXMLNamed(e *xml.Encoder, name string, v interface{}) error {
+	if msg, ok := v.(proto.Message); ok {
+		return encodeXMLMessage(e, xml.StartElement{Name: xml.Name{Local: name}}, msg.ProtoReflect())
+	}
+	return e.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: name}})
+}
+
+// encodeXMLMessage writes msg as the start element, keeping its attributes, e.g. map keys.
+func encodeXMLMessage(e *xml.Encoder, start xml.StartElement, msg protoreflect.Message) error {
+	md := msg.Descriptor()
+
+	if IsWellKnownType(md) {
+		text, err := wellKnownText(msg)
+		if err != nil {
+			return err
+		}
+		return e.EncodeElement(text, start)
+	}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !isXMLAttr(fd) {
+			continue
+		}
+		if !msg.Has(fd) && fd.HasPresence() {
+			continue
+		}
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: xmlFieldName(fd)}, Value: scalarText(fd, msg.Get(fd))})
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if isXMLAttr(fd) {
+			continue
+		}
+		if err := encodeXMLField(e, msg, fd); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func encodeXMLField(e *xml.Encoder, msg protoreflect.Message, fd protoreflect.FieldDescriptor) error {
+	name := xml.Name{Local: xmlFieldName(fd)}
+	v := msg.Get(fd)
+
+	switch {
+	case fd.IsMap():
+		m := v.Map()
+		keys := make([]protoreflect.MapKey, 0, m.Len())
+		m.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+			keys = append(keys, k)
+			return true
+		})
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			start := xml.StartElement{Name: name, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: k.String()}}}
+			if err := encodeXMLSingle(e, start, fd.MapValue(), m.Get(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case fd.IsList():
+		l := v.List()
+		for i := 0; i < l.Len(); i++ {
+			if err := encodeXMLSingle(e, xml.StartElement{Name: name}, fd, l.Get(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case fd.Message() != nil && !msg.Has(fd):
+		return nil
+	case fd.HasPresence() && !msg.Has(fd):
+		return nil
+	}
+	return encodeXMLSingle(e, xml.StartElement{Name: name}, fd, v)
+}
+
+func encodeXMLSingle(e *xml.Encoder, start xml.StartElement, fd protoreflect.FieldDescriptor, v protoreflect.Value) error {
+	if fd.Message() != nil {
+		sub := v.Message()
+		if IsWellKnownType(fd.Message()) {
+			text, err := wellKnownText(sub)
+			if err != nil {
+				return err
+			}
+			return e.EncodeElement(text, start)
+		}
+		return encodeXMLMessage(e, start, sub)
+	}
+	return e.EncodeElement(scalarText(fd, v), start)
+}
+
+func decodeXMLMessage(d *xml.Decoder, start xml.StartElement, msg protoreflect.Message) error {
+	md := msg.Descriptor()
+	if IsWellKnownType(md) {
+		var text string
+		if err := d.DecodeElement(&text, &start); err != nil {
+			return err
+		}
+		return parseWellKnownText(text, msg)
+	}
+
+	for _, attr := range start.Attr {
+		fd := xmlFieldByName(md, attr.Name.Local)
+		if fd == nil || fd.Message() != nil || fd.IsMap() {
+			continue
+		}
+		if err := setXMLScalar(msg, fd, attr.Value); err != nil {
+			return err
+		}
+	}
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			fd := xmlFieldByName(md, t.Name.Local)
+			if fd == nil {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := decodeXMLField(d, t, msg, fd); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func decodeXMLField(d *xml.Decoder, start xml.StartElement, msg protoreflect.Message, fd protoreflect.FieldDescriptor) error {
+	if fd.IsMap() {
+		var key string
+		for _, attr := range start.Attr {
+			if attr.Name.Local == "key" {
+				key = attr.Value
+			}
+		}
+		k, err := parseScalar(fd.MapKey(), key)
+		if err != nil {
+			return err
+		}
+		m := msg.Mutable(fd).Map()
+		vd := fd.MapValue()
+		if vd.Message() != nil {
+			val := m.NewValue()
+			if err := decodeXMLMessage(d, start, val.Message()); err != nil {
+				return err
+			}
+			m.Set(k.MapKey(), val)
+			return nil
+		}
+		var text string
+		if err := d.DecodeElement(&text, &start); err != nil {
+			return err
+		}
+		val, err := parseScalar(vd, text)
+		if err != nil {
+			return err
+		}
+		m.Set(k.MapKey(), val)
+		return nil
+	}
+
+	if fd.Message() != nil {
+		var val protoreflect.Value
+		if fd.IsList() {
+			val = msg.Mutable(fd).List().NewElement()
+		} else {
+			val = protoreflect.ValueOfMessage(msg.Mutable(fd).Message())
+		}
+		if err := decodeXMLMessage(d, start, val.Message()); err != nil {
+			return err
+		}
+		if fd.IsList() {
+			msg.Mutable(fd).List().Append(val)
+		}
+		return nil
+	}
+
+	var text string
+	if err := d.DecodeElement(&text, &start); err != nil {
+		return err
+	}
+	return setXMLScalar(msg, fd, text)
+}
+
+func setXMLScalar(msg protoreflect.Message, fd protoreflect.FieldDescriptor, text string) error {
+	v, err := parseScalar(fd, text)
+	if err != nil {
+		return err
+	}
+	if fd.IsList() {
+		msg.Mutable(fd).List().Append(v)
+		return nil
+	}
+	msg.Set(fd, v)
+	return nil
+}
+
+func wellKnownText(msg protoreflect.Message) (string, error) {
+	b, err := protojson.Marshal(msg.Interface())
+	if err != nil {
+		return "", err
+	}
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		return s, nil
+	}
+	return string(b), nil
+}
+
+func parseWellKnownText(text string, msg protoreflect.Message) error {
+	text = strings.TrimSpace(text)
+	if err := protojson.Unmarshal([]byte(text), msg.Interface()); err == nil {
+		return nil
+	}
+	quoted, _ := json.Marshal(text)
+	return protojson.Unmarshal(quoted, msg.Interface())
+}
+
+func xmlRootName(md protoreflect.MessageDescriptor) string {
+	if name, ok := proto.GetExtension(md.Options(), pb.E_XmlRoot).(string); ok && name != "" {
+		return name
+	}
+	return string(md.Name())
+}
+
+func xmlFieldName(fd protoreflect.FieldDescriptor) string {
+	if name, ok := proto.GetExtension(fd.Options(), pb.E_XmlName).(string); ok && name != "" {
+		return name
+	}
+	return fd.JSONName()
+}
+
+func isXMLAttr(fd protoreflect.FieldDescriptor) bool {
+	attr, _ := proto.GetExtension(fd.Options(), pb.E_XmlAttr).(bool)
+	return attr && fd.Message() == nil && !fd.IsList() && !fd.IsMap()
+}
+
+// xmlFieldByName looks a field up by its xml name, json name or proto name.
+func xmlFieldByName(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if fd := fields.Get(i); xmlFieldName(fd) == name {
+			return fd
+		}
+	}
+	return fieldByName(md, name)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,89 @@
+package gatewayopt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tikivn/tikit-go-kit/pb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// newXMLTestMessage builds the descriptor of:
+//
+//	message Callback {
+//	  option (pb.xml_root) = "callback";
+//	  string trans_id = 1 [(pb.xml_attr) = true];
+//	  int64 amount = 2;
+//	  repeated Item items = 3 [(pb.xml_name) = "item"];
+//	  message Item { string sku = 1; }
+//	}
+func newXMLTestMessage(t *testing.T) protoreflect.MessageDescriptor {
+	transID := testField("trans_id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	transID.JsonName = proto.String("transId")
+	transID.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(transID.Options, pb.E_XmlAttr, true)
+
+	items := testField("items", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	items.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	items.TypeName = proto.String(".test.Callback.Item")
+	items.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(items.Options, pb.E_XmlName, "item")
+
+	msgOpts := &descriptorpb.MessageOptions{}
+	proto.SetExtension(msgOpts, pb.E_XmlRoot, "callback")
+
+	return newTestMessage(t, "xml", &descriptorpb.DescriptorProto{
+		Name:    proto.String("Callback"),
+		Options: msgOpts,
+		Field: []*descriptorpb.FieldDescriptorProto{
+			transID,
+			testField("amount", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64),
+			items,
+		},
+		NestedType: []*descriptorpb.DescriptorProto{{
+			Name:  proto.String("Item"),
+			Field: []*descriptorpb.FieldDescriptorProto{testField("sku", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)},
+		}},
+	})
+}
+
+func TestXMLMarshaler(t *testing.T) {
+	m := &xmlMarshaler{contentType: "application/xml"}
+	md := newXMLTestMessage(t)
+
+	data := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<callback transId="T1"><amount>300000</amount><item><sku>A</sku></item><item><sku>B</sku></item></callback>`
+
+	msg := dynamicpb.NewMessage(md)
+	require.NoError(t, m.Unmarshal([]byte(data), msg))
+	assert.Equal(t, "T1", msg.Get(md.Fields().ByName("trans_id")).String())
+	assert.Equal(t, int64(300000), msg.Get(md.Fields().ByName("amount")).Int())
+	assert.Equal(t, 2, msg.Get(md.Fields().ByName("items")).List().Len())
+
+	buf, err := m.Marshal(msg)
+	require.NoError(t, err)
+	assert.Equal(t, data, string(buf))
+}
+
+func TestXMLMarshaler_stream(t *testing.T) {
+	m := &xmlMarshaler{contentType: "application/xml"}
+	md := newXMLTestMessage(t)
+	msg := dynamicpb.NewMessage(md)
+	msg.Set(md.Fields().ByName("trans_id"), protoreflect.ValueOfString("T1"))
+
+	buf, err := m.Marshal(map[string]interface{}{"result": msg})
+	require.NoError(t, err)
+	assert.Equal(t, `<result transId="T1"><amount>0</amount></result>`, string(buf))
+
+	var out bytes.Buffer
+	enc := m.NewEncoder(&out)
+	require.NoError(t, enc.Encode(msg))
+	require.NoError(t, enc.Encode(msg))
+	assert.Equal(t, 1, strings.Count(out.String(), "<?xml"))
+}
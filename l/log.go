@@ -62,6 +62,7 @@ var (
 	Uint64     = zap.Uint64
 	Uintptr    = zap.Uintptr
 	ByteString = zap.ByteString
+	Strings    = zap.Strings
 )
 
 // DefaultConsoleEncoderConfig ...
@@ -0,0 +1,145 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.0
+// 	protoc        (unknown)
+// source: error_details.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// HTTPStatus carries the HTTP status of an e.Status across gRPC,
+// the gateway error handler responds with it and drops the detail.
+type HTTPStatus struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code int32 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+}
+
+func (x *HTTPStatus) Reset() {
+	*x = HTTPStatus{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_error_details_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HTTPStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HTTPStatus) ProtoMessage() {}
+
+func (x *HTTPStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_error_details_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HTTPStatus.ProtoReflect.Descriptor instead.
+func (*HTTPStatus) Descriptor() ([]byte, []int) {
+	return file_error_details_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *HTTPStatus) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+var File_error_details_proto protoreflect.FileDescriptor
+
+var file_error_details_proto_rawDesc = []byte{
+	0x0a, 0x13, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x64, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x02, 0x70, 0x62, 0x22, 0x20, 0x0a, 0x0a, 0x48, 0x54, 0x54,
+	0x50, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x42, 0x23, 0x5a, 0x21, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x74, 0x69, 0x6b, 0x69, 0x76, 0x6e,
+	0x2f, 0x74, 0x69, 0x6b, 0x69, 0x74, 0x2d, 0x67, 0x6f, 0x2d, 0x6b, 0x69, 0x74, 0x2f, 0x70, 0x62,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_error_details_proto_rawDescOnce sync.Once
+	file_error_details_proto_rawDescData = file_error_details_proto_rawDesc
+)
+
+func file_error_details_proto_rawDescGZIP() []byte {
+	file_error_details_proto_rawDescOnce.Do(func() {
+		file_error_details_proto_rawDescData = protoimpl.X.CompressGZIP(file_error_details_proto_rawDescData)
+	})
+	return file_error_details_proto_rawDescData
+}
+
+var file_error_details_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_error_details_proto_goTypes = []interface{}{
+	(*HTTPStatus)(nil), // 0: pb.HTTPStatus
+}
+var file_error_details_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_error_details_proto_init() }
+func file_error_details_proto_init() {
+	if File_error_details_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_error_details_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HTTPStatus); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_error_details_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_error_details_proto_goTypes,
+		DependencyIndexes: file_error_details_proto_depIdxs,
+		MessageInfos:      file_error_details_proto_msgTypes,
+	}.Build()
+	File_error_details_proto = out.File
+	file_error_details_proto_rawDesc = nil
+	file_error_details_proto_goTypes = nil
+	file_error_details_proto_depIdxs = nil
+}
@@ -0,0 +1,135 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.0
+// 	protoc        (unknown)
+// source: xml_options.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+var file_xml_options_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MessageOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50800,
+		Name:          "pb.xml_root",
+		Tag:           "bytes,50800,opt,name=xml_root",
+		Filename:      "xml_options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50801,
+		Name:          "pb.xml_name",
+		Tag:           "bytes,50801,opt,name=xml_name",
+		Filename:      "xml_options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50802,
+		Name:          "pb.xml_attr",
+		Tag:           "varint,50802,opt,name=xml_attr",
+		Filename:      "xml_options.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MessageOptions.
+var (
+	// xml_root overrides the root element name, the message name by default.
+	//
+	// optional string xml_root = 50800;
+	E_XmlRoot = &file_xml_options_proto_extTypes[0]
+)
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// xml_name overrides the element or attribute name, the json_name by default.
+	//
+	// optional string xml_name = 50801;
+	E_XmlName = &file_xml_options_proto_extTypes[1]
+	// xml_attr renders a scalar field as an attribute of its parent element.
+	//
+	// optional bool xml_attr = 50802;
+	E_XmlAttr = &file_xml_options_proto_extTypes[2]
+)
+
+var File_xml_options_proto protoreflect.FileDescriptor
+
+var file_xml_options_proto_rawDesc = []byte{
+	0x0a, 0x11, 0x78, 0x6d, 0x6c, 0x5f, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x02, 0x70, 0x62, 0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70,
+	0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3a, 0x3c, 0x0a, 0x08, 0x78, 0x6d, 0x6c,
+	0x5f, 0x72, 0x6f, 0x6f, 0x74, 0x12, 0x1f, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x4f,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xf0, 0x8c, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x78, 0x6d, 0x6c, 0x52, 0x6f, 0x6f, 0x74, 0x3a, 0x3a, 0x0a, 0x08, 0x78, 0x6d, 0x6c, 0x5f, 0x6e,
+	0x61, 0x6d, 0x65, 0x12, 0x1d, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x18, 0xf1, 0x8c, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x78, 0x6d, 0x6c, 0x4e,
+	0x61, 0x6d, 0x65, 0x3a, 0x3a, 0x0a, 0x08, 0x78, 0x6d, 0x6c, 0x5f, 0x61, 0x74, 0x74, 0x72, 0x12,
+	0x1d, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xf2,
+	0x8c, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x78, 0x6d, 0x6c, 0x41, 0x74, 0x74, 0x72, 0x42,
+	0x64, 0x0a, 0x06, 0x63, 0x6f, 0x6d, 0x2e, 0x70, 0x62, 0x42, 0x0f, 0x58, 0x6d, 0x6c, 0x4f, 0x70,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x21, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x74, 0x69, 0x6b, 0x69, 0x76, 0x6e, 0x2f,
+	0x74, 0x69, 0x6b, 0x69, 0x74, 0x2d, 0x67, 0x6f, 0x2d, 0x6b, 0x69, 0x74, 0x2f, 0x70, 0x62, 0xa2,
+	0x02, 0x03, 0x50, 0x58, 0x58, 0xaa, 0x02, 0x02, 0x50, 0x62, 0xca, 0x02, 0x02, 0x50, 0x62, 0xe2,
+	0x02, 0x0e, 0x50, 0x62, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61,
+	0xea, 0x02, 0x02, 0x50, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var file_xml_options_proto_goTypes = []interface{}{
+	(*descriptorpb.MessageOptions)(nil), // 0: google.protobuf.MessageOptions
+	(*descriptorpb.FieldOptions)(nil),   // 1: google.protobuf.FieldOptions
+}
+var file_xml_options_proto_depIdxs = []int32{
+	0, // 0: pb.xml_root:extendee -> google.protobuf.MessageOptions
+	1, // 1: pb.xml_name:extendee -> google.protobuf.FieldOptions
+	1, // 2: pb.xml_attr:extendee -> google.protobuf.FieldOptions
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	0, // [0:3] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_xml_options_proto_init() }
+func file_xml_options_proto_init() {
+	if File_xml_options_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_xml_options_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   0,
+			NumExtensions: 3,
+			NumServices:   0,
+		},
+		GoTypes:           file_xml_options_proto_goTypes,
+		DependencyIndexes: file_xml_options_proto_depIdxs,
+		ExtensionInfos:    file_xml_options_proto_extTypes,
+	}.Build()
+	File_xml_options_proto = out.File
+	file_xml_options_proto_rawDesc = nil
+	file_xml_options_proto_goTypes = nil
+	file_xml_options_proto_depIdxs = nil
+}
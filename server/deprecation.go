@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tikivn/tikit-go-kit/grpc/gatewayopt"
+	"github.com/tikivn/tikit-go-kit/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// deprecation holds the response headers of a deprecated method.
+type deprecation struct {
+	deprecated bool
+	sunset     string
+}
+
+// deprecations reads the deprecation of methods from files, caching it by full method name.
+type deprecations struct {
+	files *protoregistry.Files
+	cache sync.Map // full method name -> deprecation
+}
+
+var globalDeprecations = &deprecations{files: protoregistry.GlobalFiles}
+
+// method reads `option deprecated = true` and the (pb.sunset) option of a method.
+func (ds *deprecations) method(fullMethod string) deprecation {
+	if d, ok := ds.cache.Load(fullMethod); ok {
+		return d.(deprecation)
+	}
+
+	var d deprecation
+	name := strings.Replace(strings.TrimPrefix(fullMethod, "/"), "/", ".", 1)
+	if desc, err := ds.files.FindDescriptorByName(protoreflect.FullName(name)); err == nil {
+		if md, ok := desc.(protoreflect.MethodDescriptor); ok {
+			opts, _ := md.Options().(*descriptorpb.MethodOptions)
+			d.deprecated = opts.GetDeprecated()
+			if sunset, _ := proto.GetExtension(opts, pb.E_Sunset).(string); d.deprecated && sunset != "" {
+				d.sunset = formatSunset(sunset)
+			}
+		}
+	}
+	ds.cache.Store(fullMethod, d)
+	return d
+}
+
+// formatSunset converts an RFC 3339 date or time to the HTTP date of the Sunset header.
+func formatSunset(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.Parse("2006-01-02", value); err != nil {
+			ll.Warn("Invalid sunset option " + value)
+			return ""
+		}
+	}
+	return t.UTC().Format(http.TimeFormat)
+}
+
+func (ds *deprecations) setHeaders(ctx context.Context, fullMethod string) {
+	d := ds.method(fullMethod)
+	if !d.deprecated {
+		return
+	}
+	_ = gatewayopt.SetHeader(ctx, "Deprecation", "true")
+	if d.sunset != "" {
+		_ = gatewayopt.SetHeader(ctx, "Sunset", d.sunset)
+	}
+}
+
+// DeprecationUnaryServerInterceptor adds the Deprecation and Sunset headers to responses of methods
+// marked `option deprecated = true`, with the date of the (pb.sunset) option.
+// The gateway writes them with gatewayopt.ResponseControl, on error responses too, gRPC clients receive them
+// as header metadata.
+func DeprecationUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return globalDeprecations.unaryServerInterceptor()
+}
+
+// DeprecationStreamServerInterceptor is the stream counterpart of DeprecationUnaryServerInterceptor.
+func DeprecationStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return globalDeprecations.streamServerInterceptor()
+}
+
+func (ds *deprecations) unaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ds.setHeaders(ctx, info.FullMethod)
+		return handler(ctx, req)
+	}
+}
+
+func (ds *deprecations) streamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ds.setHeaders(ss.Context(), info.FullMethod)
+		return handler(srv, ss)
+	}
+}
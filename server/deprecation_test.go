@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tikivn/tikit-go-kit/e"
+	"github.com/tikivn/tikit-go-kit/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// deprecatedLivenessFiles holds the health service with Liveness deprecated until 2025-06-30.
+func deprecatedLivenessFiles(t *testing.T) *protoregistry.Files {
+	fdp := protodesc.ToFileDescriptorProto(pb.File_svc_health_proto)
+	for _, m := range fdp.Service[0].Method {
+		if m.GetName() == "Liveness" {
+			m.Options = &descriptorpb.MethodOptions{Deprecated: proto.Bool(true)}
+			proto.SetExtension(m.Options, pb.E_Sunset, "2025-06-30")
+		}
+	}
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	files := &protoregistry.Files{}
+	require.NoError(t, files.RegisterFile(fd))
+	return files
+}
+
+func TestDeprecationUnaryServerInterceptor(t *testing.T) {
+	ds := &deprecations{files: deprecatedLivenessFiles(t)}
+	withDeprecations := func(c *Config) {
+		c.Grpc.ServerUnaryInterceptors = append(c.Grpc.ServerUnaryInterceptors, ds.unaryServerInterceptor())
+	}
+
+	tests := []struct {
+		name       string
+		server     ServiceServer
+		path       string
+		wantStatus int
+		deprecated bool
+	}{
+		{"deprecated", &testHealthServer{}, "/health", http.StatusOK, true},
+		{"deprecated error", &errorHealthServer{err: e.Error(codes.NotFound, "gone")}, "/health", http.StatusNotFound, true},
+		{"not deprecated", &testHealthServer{}, "/ready", http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := createConfig([]Option{WithServiceServer(tt.server), withDeprecations})
+			s, conn := newTestBackend(t, c)
+			gw, err := newGatewayServer(c.Gateway, s, conn, c.ServiceServers)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			gw.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if !tt.deprecated {
+				assert.Empty(t, w.Header().Values("Deprecation"))
+				return
+			}
+			assert.Equal(t, "true", w.Header().Get("Deprecation"))
+			assert.Equal(t, "Mon, 30 Jun 2025 00:00:00 GMT", w.Header().Get("Sunset"))
+		})
+	}
+}
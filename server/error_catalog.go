@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/tikivn/tikit-go-kit/e"
+)
+
+// ErrorCatalogPath is where ErrorCatalogHandler lists the registered errors.
+const ErrorCatalogPath = "/errors"
+
+type errorCatalogEntry struct {
+	Reason      string   `json:"reason"`
+	Domain      string   `json:"domain"`
+	Code        string   `json:"code"`
+	HTTPStatus  int      `json:"httpStatus"`
+	Message     string   `json:"message"`
+	Params      []string `json:"params,omitempty"`
+	Retryable   bool     `json:"retryable"`
+	Description string   `json:"description,omitempty"`
+}
+
+// ErrorCatalogHandler lists the errors of the catalogs at ErrorCatalogPath, as JSON
+// or as markdown tables with "?format=markdown" or "Accept: text/markdown".
+func ErrorCatalogHandler(catalogs ...*e.Catalog) HTTPServerHandler {
+	return func(mux *http.ServeMux) {
+		mux.HandleFunc(ErrorCatalogPath, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("format") == "markdown" || strings.Contains(r.Header.Get("Accept"), "text/markdown") {
+				w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+				for i, c := range catalogs {
+					if i > 0 {
+						_, _ = w.Write([]byte("\n"))
+					}
+					_, _ = w.Write([]byte(c.Markdown()))
+				}
+				return
+			}
+
+			entries := []errorCatalogEntry{}
+			for _, c := range catalogs {
+				for _, entry := range c.Entries() {
+					httpStatus := entry.HTTPStatus
+					if httpStatus == 0 {
+						httpStatus = e.DefaultHTTPStatus(entry.Code)
+					}
+					entries = append(entries, errorCatalogEntry{
+						Reason:      entry.Reason,
+						Domain:      entry.Domain,
+						Code:        entry.Code.String(),
+						HTTPStatus:  httpStatus,
+						Message:     entry.Message,
+						Params:      entry.Params,
+						Retryable:   entry.Retryable,
+						Description: entry.Description,
+					})
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": entries})
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tikivn/tikit-go-kit/e"
+	"google.golang.org/grpc/codes"
+)
+
+func TestErrorCatalogHandler(t *testing.T) {
+	catalog := e.NewCatalog("server.test")
+	catalog.Register(e.Def{Reason: "ORDER_NOT_FOUND", Code: codes.NotFound, HTTPStatus: http.StatusNotFound, Message: "order {order_id} not found", Params: []string{"order_id"}})
+
+	mux := http.NewServeMux()
+	ErrorCatalogHandler(catalog)(mux)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, ErrorCatalogPath, nil))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"errors": [{
+		"reason": "ORDER_NOT_FOUND",
+		"domain": "server.test",
+		"code": "NotFound",
+		"httpStatus": 404,
+		"message": "order {order_id} not found",
+		"params": ["order_id"],
+		"retryable": false
+	}]}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, ErrorCatalogPath+"?format=markdown", nil))
+	assert.Equal(t, catalog.Markdown(), w.Body.String())
+}
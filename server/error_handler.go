@@ -5,10 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/tikivn/tikit-go-kit/e"
+	"github.com/tikivn/tikit-go-kit/grpc/gatewayopt"
+	"github.com/tikivn/tikit-go-kit/pb"
+	// resolves the google.rpc error details rendered in responses
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/grpclog"
 	"google.golang.org/grpc/status"
-	"io"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 	"net/http"
 	"net/textproto"
 	"strings"
@@ -25,39 +32,77 @@ func DefaultRoutingErrorHandler(ctx context.Context, mux *runtime.ServeMux, mars
 		sterr = status.Error(codes.NotFound, http.StatusText(httpStatus))
 	}
 
-	DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, sterr)
+	if mux == nil {
+		DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, sterr)
+		return
+	}
+	// the error handler configured on the mux renders routing errors too
+	runtime.HTTPError(ctx, mux, marshaler, w, r, sterr)
 }
 
+// DefaultHTTPErrorHandler renders errors as google.rpc.Status with the request marshaler, see StatusErrorRenderer.
 func DefaultHTTPErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
-	// return Internal when Marshal failed
-	const fallback = `{"code": 13, "message": "failed to marshal error message"}`
+	renderHTTPError(ctx, StatusErrorRenderer(), marshaler, w, r, err)
+}
+
+// renderHTTPError writes err in the format of the renderer, its fallback when rendering fails.
+func renderHTTPError(ctx context.Context, renderer ErrorRenderer, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	he := resolveHTTPError(err)
+	recordGatewayError(ctx, he.status, he.httpStatus)
+
+	contentType, buf, rerr := renderer.Render(ctx, r, marshaler, he.status, he.httpStatus)
+	if rerr != nil {
+		grpclog.Infof("Failed to marshal error message %q: %v", he.status, rerr)
+		contentType, buf = renderer.RenderFallback(marshaler)
+		w.Header().Del("Trailer")
+		w.Header().Del("Transfer-Encoding")
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := w.Write(buf); err != nil {
+			grpclog.Infof("Failed to write response: %v", err)
+		}
+		return
+	}
 
+	writeHTTPError(ctx, w, r, he, contentType, buf)
+}
+
+// httpError is an error resolved for a REST response.
+type httpError struct {
+	// status is without the pb.HTTPStatus detail
+	status     *status.Status
+	httpStatus int
+}
+
+// resolveHTTPError converts err to a status and picks the HTTP status of the response,
+// set with runtime.HTTPStatusError, attached by an e.Status, or mapped from the code.
+func resolveHTTPError(err error) httpError {
 	var customStatus *runtime.HTTPStatusError
 	if errors.As(err, &customStatus) {
 		err = customStatus.Err
 	}
 
 	s := status.Convert(err)
-	pb := s.Proto()
+	sp, detailStatus := extractHTTPStatus(s.Proto())
 
+	st := e.HTTPStatusFromCode(s.Code())
+	if customStatus != nil {
+		st = customStatus.HTTPStatus
+	} else if detailStatus != 0 {
+		st = detailStatus
+	}
+	return httpError{status: status.FromProto(sp), httpStatus: st}
+}
+
+// writeHTTPError writes an error body along with the header and trailer metadata of the call.
+func writeHTTPError(ctx context.Context, w http.ResponseWriter, r *http.Request, he httpError, contentType string, buf []byte) {
 	w.Header().Del("Trailer")
 	w.Header().Del("Transfer-Encoding")
 
-	contentType := marshaler.ContentType(pb)
 	w.Header().Set("Content-Type", contentType)
 
-	if s.Code() == codes.Unauthenticated {
-		w.Header().Set("WWW-Authenticate", s.Message())
-	}
-
-	buf, merr := marshaler.Marshal(pb)
-	if merr != nil {
-		grpclog.Infof("Failed to marshal error message %q: %v", s, merr)
-		w.WriteHeader(http.StatusInternalServerError)
-		if _, err := io.WriteString(w, fallback); err != nil {
-			grpclog.Infof("Failed to write response: %v", err)
-		}
-		return
+	if he.status.Code() == codes.Unauthenticated {
+		w.Header().Set("WWW-Authenticate", he.status.Message())
 	}
 
 	md, ok := runtime.ServerMetadataFromContext(ctx)
@@ -65,7 +110,8 @@ func DefaultHTTPErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshal
 		grpclog.Infof("Failed to extract ServerMetadata from context")
 	}
 
-	handleForwardResponseServerMetadata(w, mux, md)
+	gatewayopt.ApplyErrorResponseControl(ctx, w)
+	handleForwardResponseServerMetadata(w, md)
 
 	// RFC 7230 https://tools.ietf.org/html/rfc7230#section-4.1.2
 	// Unless the request includes a TE header field indicating "trailers"
@@ -79,12 +125,7 @@ func DefaultHTTPErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshal
 		w.Header().Set("Transfer-Encoding", "chunked")
 	}
 
-	st := HTTPStatusFromCode(s.Code())
-	if customStatus != nil {
-		st = customStatus.HTTPStatus
-	}
-
-	w.WriteHeader(st)
+	w.WriteHeader(he.httpStatus)
 	if _, err := w.Write(buf); err != nil {
 		grpclog.Infof("Failed to write response: %v", err)
 	}
@@ -94,7 +135,32 @@ func DefaultHTTPErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshal
 	}
 }
 
-func handleForwardResponseServerMetadata(w http.ResponseWriter, mux *runtime.ServeMux, md runtime.ServerMetadata) {
+// extractHTTPStatus returns the HTTP status an e.Status attached as a pb.HTTPStatus detail
+// and the status without that detail, which is not meant for REST callers.
+func extractHTTPStatus(sp *spb.Status) (*spb.Status, int) {
+	httpStatus := 0
+	details := make([]*anypb.Any, 0, len(sp.GetDetails()))
+	for _, d := range sp.GetDetails() {
+		var hs pb.HTTPStatus
+		if d.MessageIs(&hs) {
+			if d.UnmarshalTo(&hs) == nil && hs.Code > 0 {
+				httpStatus = int(hs.Code)
+			}
+			continue
+		}
+		details = append(details, d)
+	}
+	if len(details) == len(sp.GetDetails()) {
+		return sp, httpStatus
+	}
+
+	out := proto.Clone(sp).(*spb.Status)
+	out.Details = details
+	return out, httpStatus
+}
+
+// handleForwardResponseServerMetadata writes header metadata as Grpc-Metadata- headers, like the gateway does for responses.
+func handleForwardResponseServerMetadata(w http.ResponseWriter, md runtime.ServerMetadata) {
 	for k, vs := range md.HeaderMD {
 		if h, ok := runtime.DefaultHeaderMatcher(k); ok {
 			for _, v := range vs {
@@ -125,46 +191,7 @@ func requestAcceptsTrailers(req *http.Request) bool {
 	return strings.Contains(strings.ToLower(te), "trailers")
 }
 
+// HTTPStatusFromCode returns the HTTP status of a gRPC code, see e.HTTPStatusFromCode.
 func HTTPStatusFromCode(code codes.Code) int {
-	switch code {
-	case codes.OK:
-		return http.StatusOK
-	case codes.Canceled:
-		return http.StatusRequestTimeout
-	case codes.Unknown:
-		return http.StatusInternalServerError
-	case codes.InvalidArgument:
-		return http.StatusBadRequest
-	case codes.DeadlineExceeded:
-		return http.StatusGatewayTimeout
-	case codes.NotFound:
-		return http.StatusNotFound
-	case codes.AlreadyExists:
-		return http.StatusConflict
-	case codes.PermissionDenied:
-		return http.StatusForbidden
-	case codes.Unauthenticated:
-		return http.StatusUnauthorized
-	case codes.ResourceExhausted:
-		return http.StatusTooManyRequests
-	case codes.FailedPrecondition:
-		// Note, this deliberately doesn't translate to the similarly named '412 Precondition Failed' HTTP response status.
-		return http.StatusBadRequest
-	case codes.Aborted:
-		return http.StatusConflict
-	case codes.OutOfRange:
-		return http.StatusBadRequest
-	case codes.Unimplemented:
-		//todo: change  http.StatusNotImplemented -> http.StatusMethodNotAllowed
-		return http.StatusMethodNotAllowed
-	case codes.Internal:
-		return http.StatusInternalServerError
-	case codes.Unavailable:
-		return http.StatusServiceUnavailable
-	case codes.DataLoss:
-		return http.StatusInternalServerError
-	}
-
-	grpclog.Infof("Unknown gRPC error code: %v", code)
-	return http.StatusInternalServerError
+	return e.HTTPStatusFromCode(code)
 }
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tikivn/tikit-go-kit/e"
+	"github.com/tikivn/tikit-go-kit/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDefaultHTTPErrorHandler_roundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"client error", e.Error(codes.NotFound, "order not found").ClientErr(), http.StatusBadRequest},
+		{"server error", e.Error(codes.InvalidArgument, "upstream failed").ServerErr(), http.StatusInternalServerError},
+		{"custom status", e.Error(codes.FailedPrecondition, "order locked").SetHttpStatus(http.StatusLocked), http.StatusLocked},
+		{"default status", e.Error(codes.PermissionDenied, "not your order"), http.StatusForbidden},
+		{"plain status", &e.Status{Err: e.Error(codes.NotFound, "order not found").Err}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := createConfig([]Option{WithServiceServer(&errorHealthServer{err: tt.err})})
+			s, conn := newTestBackend(t, c)
+			gw, err := newGatewayServer(c.Gateway, s, conn, c.ServiceServers)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			gw.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			assert.Equal(t, tt.want, w.Code)
+
+			var body struct {
+				Code    int               `json:"code"`
+				Message string            `json:"message"`
+				Details []json.RawMessage `json:"details"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, int(tt.err.(*e.Status).Code()), body.Code)
+			assert.Equal(t, tt.err.(*e.Status).Message(), body.Message)
+			assert.Empty(t, body.Details, "the HTTP status detail is not exposed")
+		})
+	}
+}
+
+// TestDefaultHTTPErrorHandler_codes pins the statuses of e errors without an explicit HTTP status
+// to those of plain gRPC errors with the same code.
+func TestDefaultHTTPErrorHandler_codes(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{codes.Canceled, http.StatusRequestTimeout},
+		{codes.Unknown, http.StatusInternalServerError},
+		{codes.InvalidArgument, http.StatusBadRequest},
+		{codes.DeadlineExceeded, http.StatusGatewayTimeout},
+		{codes.NotFound, http.StatusNotFound},
+		{codes.AlreadyExists, http.StatusConflict},
+		{codes.PermissionDenied, http.StatusForbidden},
+		{codes.Unauthenticated, http.StatusUnauthorized},
+		{codes.ResourceExhausted, http.StatusTooManyRequests},
+		{codes.FailedPrecondition, http.StatusBadRequest},
+		{codes.Aborted, http.StatusConflict},
+		{codes.OutOfRange, http.StatusBadRequest},
+		{codes.Internal, http.StatusInternalServerError},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.DataLoss, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			for _, err := range []error{status.Error(tt.code, "failed"), e.Error(tt.code, "failed")} {
+				c := createConfig([]Option{WithServiceServer(&errorHealthServer{err: err})})
+				s, conn := newTestBackend(t, c)
+				gw, gerr := newGatewayServer(c.Gateway, s, conn, c.ServiceServers)
+				require.NoError(t, gerr)
+
+				w := httptest.NewRecorder()
+				gw.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+				assert.Equal(t, tt.want, w.Code, "%T", err)
+			}
+		})
+	}
+}
+
+func TestDefaultHTTPErrorHandler_inProcess(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	err := e.Error(codes.Unauthenticated, "token expired").SetHttpStatus(http.StatusForbidden)
+
+	DefaultHTTPErrorHandler(r.Context(), nil, &runtime.JSONPb{}, w, r, err)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "token expired", w.Header().Get("WWW-Authenticate"))
+}
+
+func TestDefaultHTTPErrorHandler_details(t *testing.T) {
+	err := e.Error(codes.InvalidArgument, "invalid order").
+		WithFieldViolation("customer.phone", "invalid phone number").
+		WithReason("ORDER_INVALID", "order.tiki.vn", nil).
+		SetHttpStatus(http.StatusUnprocessableEntity)
+	c := createConfig([]Option{WithServiceServer(&errorHealthServer{err: err})})
+	s, conn := newTestBackend(t, c)
+	gw, gerr := newGatewayServer(c.Gateway, s, conn, c.ServiceServers)
+	require.NoError(t, gerr)
+
+	w := httptest.NewRecorder()
+	gw.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(t, `{
+		"code": 3,
+		"message": "invalid order",
+		"details": [
+			{
+				"@type": "type.googleapis.com/google.rpc.BadRequest",
+				"fieldViolations": [{"field": "customer.phone", "description": "invalid phone number"}]
+			},
+			{
+				"@type": "type.googleapis.com/google.rpc.ErrorInfo",
+				"reason": "ORDER_INVALID",
+				"domain": "order.tiki.vn",
+				"metadata": {}
+			}
+		]
+	}`, w.Body.String())
+
+	// gRPC clients read the same details
+	_, cerr := pb.NewHealthServiceClient(conn).Liveness(context.Background(), &pb.LivenessRequest{})
+	got := e.Status{Err: status.Convert(cerr)}
+	assert.Equal(t, "ORDER_INVALID", got.Reason())
+	require.Len(t, got.FieldViolations(), 1)
+}
+
+func TestDefaultHTTPErrorHandler_notAcceptable(t *testing.T) {
+	c := createConfig([]Option{WithServiceServer(&testHealthServer{}), WithContentNegotiation()})
+	s, conn := newTestBackend(t, c)
+	gw, err := newGatewayServer(c.Gateway, s, conn, c.ServiceServers)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/health", nil)
+	r.Header.Set("Accept", "text/html")
+	w := httptest.NewRecorder()
+	gw.server.Handler.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	assert.JSONEq(t, `{
+		"code": 3,
+		"message": "supported media types: application/json, application/x-protobuf, application/x-www-form-urlencoded",
+		"details": []
+	}`, w.Body.String())
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/tikivn/tikit-go-kit/e"
+	"github.com/tikivn/tikit-go-kit/l"
+	"google.golang.org/grpc"
+)
+
+// logErrorCause logs the cause chain and stack of an e.Status returned by a handler,
+// which the client only sees the status of.
+func logErrorCause(fullMethod string, err error) {
+	var s *e.Status
+	if !errors.As(err, &s) || s.Cause() == nil {
+		return
+	}
+	ll.Error("gRPC call failed",
+		l.String("method", fullMethod),
+		l.String("code", s.Code().String()),
+		l.String("message", s.Message()),
+		l.String("cause", strings.Join(s.CauseChain(), " <- ")),
+		l.Strings("stack", s.StackTrace()),
+	)
+}
+
+// ErrorLogUnaryServerInterceptor logs the cause chain and stack trace of e.Status errors, see e.Status.WithCause.
+func ErrorLogUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			logErrorCause(info.FullMethod, err)
+		}
+		return resp, err
+	}
+}
+
+// ErrorLogStreamServerInterceptor is the stream counterpart of ErrorLogUnaryServerInterceptor.
+func ErrorLogStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		if err != nil {
+			logErrorCause(info.FullMethod, err)
+		}
+		return err
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tikivn/tikit-go-kit/e"
+	"github.com/tikivn/tikit-go-kit/l"
+	"github.com/tikivn/tikit-go-kit/pb"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorLogUnaryServerInterceptor(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	defer func(logger l.Logger) { ll = logger }(ll)
+	ll = l.Logger{Logger: zap.New(core)}
+
+	cause := errors.New("pq: password authentication failed")
+	c := createConfig([]Option{
+		WithServiceServer(&errorHealthServer{err: e.Error(codes.Internal, "failed to check health").WithCause(cause)}),
+		WithErrorLogging(),
+	})
+	_, conn := newTestBackend(t, c)
+
+	_, err := pb.NewHealthServiceClient(conn).Liveness(context.Background(), &pb.LivenessRequest{})
+	st := status.Convert(err)
+	assert.Equal(t, codes.Internal, st.Code())
+	assert.Equal(t, "failed to check health", st.Message())
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "/pb.HealthService/Liveness", fields["method"])
+	assert.Equal(t, cause.Error(), fields["cause"])
+	assert.NotEmpty(t, fields["stack"])
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tikivn/tikit-go-kit/e"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultMaxErrorReasons = 100
+	// otherErrorReason replaces the reasons seen after the limit
+	otherErrorReason = "OTHER"
+)
+
+var (
+	errorMetricLabels = []string{"grpc_method", "grpc_code", "http_status", "reason"}
+
+	serverErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_errors_total",
+		Help: "Total number of errors returned by gRPC handlers, by method, code, HTTP status and reason.",
+	}, errorMetricLabels)
+	gatewayErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_gateway_errors_total",
+		Help: "Total number of error responses of the gateway, by method, code, HTTP status and reason.",
+	}, errorMetricLabels)
+)
+
+func init() {
+	prometheus.MustRegister(serverErrors, gatewayErrors)
+}
+
+// ErrorMetricsConfig configures the error metrics.
+type ErrorMetricsConfig struct {
+	// MaxReasons limits the distinct reasons recorded, later ones are recorded as "OTHER". 100 by default.
+	MaxReasons int
+}
+
+// errorMetrics records errors with a bounded set of reasons.
+type errorMetrics struct {
+	maxReasons int
+
+	mu      sync.Mutex
+	reasons map[string]struct{}
+}
+
+func newErrorMetrics(cfg ErrorMetricsConfig) *errorMetrics {
+	if cfg.MaxReasons <= 0 {
+		cfg.MaxReasons = defaultMaxErrorReasons
+	}
+	return &errorMetrics{maxReasons: cfg.MaxReasons, reasons: map[string]struct{}{}}
+}
+
+func (m *errorMetrics) reason(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.reasons[reason]; ok {
+		return reason
+	}
+	if len(m.reasons) >= m.maxReasons {
+		return otherErrorReason
+	}
+	m.reasons[reason] = struct{}{}
+	return reason
+}
+
+func (m *errorMetrics) record(counter *prometheus.CounterVec, method string, s *e.Status) {
+	counter.WithLabelValues(method, s.Code().String(), strconv.Itoa(s.HTTPStatus), m.reason(s.Reason())).Inc()
+}
+
+func (m *errorMetrics) recordServerError(method string, err error) {
+	s, _ := e.FromError(err)
+	m.record(serverErrors, method, s)
+}
+
+// ErrorMetricsUnaryServerInterceptor counts handler errors in grpc_server_errors_total
+// by method, gRPC code, HTTP status and reason, see e.Status.WithReason.
+func ErrorMetricsUnaryServerInterceptor(cfg ErrorMetricsConfig) grpc.UnaryServerInterceptor {
+	return newErrorMetrics(cfg).unaryServerInterceptor()
+}
+
+// ErrorMetricsStreamServerInterceptor is the stream counterpart of ErrorMetricsUnaryServerInterceptor.
+func ErrorMetricsStreamServerInterceptor(cfg ErrorMetricsConfig) grpc.StreamServerInterceptor {
+	return newErrorMetrics(cfg).streamServerInterceptor()
+}
+
+// ErrorMetricsMiddleware lets the kit error handlers count gateway error responses in http_gateway_errors_total,
+// by gRPC method, "none" for routing errors, code, HTTP status and reason.
+func ErrorMetricsMiddleware(cfg ErrorMetricsConfig) HTTPServerMiddleware {
+	return newErrorMetrics(cfg).middleware
+}
+
+func (m *errorMetrics) unaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			m.recordServerError(info.FullMethod, err)
+		}
+		return resp, err
+	}
+}
+
+func (m *errorMetrics) streamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		if err != nil {
+			m.recordServerError(info.FullMethod, err)
+		}
+		return err
+	}
+}
+
+type errorMetricsKey struct{}
+
+func (m *errorMetrics) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), errorMetricsKey{}, m)))
+	})
+}
+
+// recordGatewayError counts an error response when ErrorMetricsMiddleware is installed.
+func recordGatewayError(ctx context.Context, st *status.Status, httpStatus int) {
+	m, ok := ctx.Value(errorMetricsKey{}).(*errorMetrics)
+	if !ok {
+		return
+	}
+	method, ok := runtime.RPCMethod(ctx)
+	if !ok {
+		method = "none"
+	}
+	m.record(gatewayErrors, method, &e.Status{HTTPStatus: httpStatus, Err: st})
+}
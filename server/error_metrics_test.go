@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tikivn/tikit-go-kit/e"
+	"github.com/tikivn/tikit-go-kit/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithErrorMetrics(t *testing.T) {
+	err := e.Error(codes.FailedPrecondition, "order locked").WithReason("ORDER_LOCKED", "order.tiki.vn", nil).SetHttpStatus(http.StatusLocked)
+	c := createConfig([]Option{
+		WithServiceServer(&errorHealthServer{err: err}),
+		WithErrorMetrics(ErrorMetricsConfig{}),
+	})
+	s, conn := newTestBackend(t, c)
+	gw, gerr := newGatewayServer(c.Gateway, s, conn, c.ServiceServers)
+	require.NoError(t, gerr)
+
+	server := serverErrors.WithLabelValues("/pb.HealthService/Liveness", "FailedPrecondition", "423", "ORDER_LOCKED")
+	gateway := gatewayErrors.WithLabelValues("/pb.HealthService/Liveness", "FailedPrecondition", "423", "ORDER_LOCKED")
+	routing := gatewayErrors.WithLabelValues("none", "NotFound", "404", "")
+	serverBefore, gatewayBefore, routingBefore := testutil.ToFloat64(server), testutil.ToFloat64(gateway), testutil.ToFloat64(routing)
+
+	for _, path := range []string{"/health", "/missing"} {
+		w := httptest.NewRecorder()
+		gw.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, serverBefore+1, testutil.ToFloat64(server))
+	assert.Equal(t, gatewayBefore+1, testutil.ToFloat64(gateway))
+	assert.Equal(t, routingBefore+1, testutil.ToFloat64(routing))
+}
+
+func TestWithErrorMetrics_order(t *testing.T) {
+	errDB := errors.New("pq: connection refused")
+	c := createConfig([]Option{
+		WithServiceServer(&errorHealthServer{err: fmt.Errorf("get order: %w", errDB)}),
+		WithErrorTranslation(ErrorIsRule(errDB, codes.Internal, "database unavailable")),
+		WithErrorMetrics(ErrorMetricsConfig{}),
+		WithErrorSanitization(SanitizeConfig{}),
+	})
+	_, conn := newTestBackend(t, c)
+
+	translated := serverErrors.WithLabelValues("/pb.HealthService/Liveness", "Internal", "500", "")
+	sanitized := serverErrors.WithLabelValues("/pb.HealthService/Liveness", "Internal", "500", SanitizedReason)
+	translatedBefore, sanitizedBefore := testutil.ToFloat64(translated), testutil.ToFloat64(sanitized)
+
+	_, err := pb.NewHealthServiceClient(conn).Liveness(context.Background(), &pb.LivenessRequest{})
+	assert.Equal(t, SanitizedReason, (&e.Status{Err: status.Convert(err)}).Reason())
+	assert.Equal(t, translatedBefore+1, testutil.ToFloat64(translated), "counted once translated")
+	assert.Equal(t, sanitizedBefore, testutil.ToFloat64(sanitized), "counted before sanitized")
+}
+
+func TestErrorMetrics_reasonLimit(t *testing.T) {
+	m := newErrorMetrics(ErrorMetricsConfig{MaxReasons: 2})
+
+	assert.Equal(t, "A", m.reason("A"))
+	assert.Equal(t, "B", m.reason("B"))
+	assert.Equal(t, otherErrorReason, m.reason("C"))
+	assert.Equal(t, "A", m.reason("A"))
+	assert.Equal(t, "", m.reason(""))
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/tikivn/tikit-go-kit/e"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorRenderer renders the body of gateway error responses.
+type ErrorRenderer interface {
+	// Render returns the content type and body of an error answered with httpStatus,
+	// marshaler is the one the gateway picked for the request.
+	Render(ctx context.Context, r *http.Request, marshaler runtime.Marshaler, s *status.Status, httpStatus int) (contentType string, body []byte, err error)
+	// RenderFallback returns the body of a 500 Internal Server Error sent when Render fails.
+	RenderFallback(marshaler runtime.Marshaler) (contentType string, body []byte)
+}
+
+// HTTPErrorHandler returns an error handler writing error bodies with the renderer,
+// the status, header and trailer metadata are handled like DefaultHTTPErrorHandler does.
+func HTTPErrorHandler(renderer ErrorRenderer) runtime.ErrorHandlerFunc {
+	return func(ctx context.Context, _ *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+		renderHTTPError(ctx, renderer, marshaler, w, r, err)
+	}
+}
+
+type statusErrorRenderer struct{}
+
+// StatusErrorRenderer renders the google.rpc.Status of errors with the request marshaler,
+// e.g. {"code": 5, "message": "order not found", "details": []}.
+func StatusErrorRenderer() ErrorRenderer {
+	return statusErrorRenderer{}
+}
+
+func (statusErrorRenderer) Render(_ context.Context, _ *http.Request, marshaler runtime.Marshaler, s *status.Status, _ int) (string, []byte, error) {
+	sp := s.Proto()
+	buf, err := marshaler.Marshal(sp)
+	return marshaler.ContentType(sp), buf, err
+}
+
+func (statusErrorRenderer) RenderFallback(marshaler runtime.Marshaler) (string, []byte) {
+	return marshaler.ContentType(nil), []byte(`{"code": 13, "message": "failed to marshal error message"}`)
+}
+
+type legacyErrorRenderer struct{}
+
+type legacyErrorBody struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Success bool `json:"success"`
+}
+
+// LegacyErrorRenderer renders {"error": {"code": "...", "message": "..."}, "success": false} as JSON
+// for older clients. The code is the reason of the error, see e.Status.WithReason, or the gRPC code name.
+func LegacyErrorRenderer() ErrorRenderer {
+	return legacyErrorRenderer{}
+}
+
+func (legacyErrorRenderer) Render(_ context.Context, _ *http.Request, _ runtime.Marshaler, s *status.Status, _ int) (string, []byte, error) {
+	var body legacyErrorBody
+	body.Error.Code = (e.Status{Err: s}).Reason()
+	if body.Error.Code == "" {
+		body.Error.Code = s.Code().String()
+	}
+	body.Error.Message = s.Message()
+	buf, err := json.Marshal(body)
+	return "application/json", buf, err
+}
+
+func (legacyErrorRenderer) RenderFallback(runtime.Marshaler) (string, []byte) {
+	return "application/json", []byte(`{"error":{"code":"Internal","message":"failed to marshal error message"},"success":false}`)
+}
+
+type minimalErrorRenderer struct{}
+
+type minimalErrorBody struct {
+	Code    int32  `json:"code"`
+	Message string `json:"message"`
+}
+
+// MinimalErrorRenderer renders {"code": 5, "message": "..."} as JSON, without details.
+func MinimalErrorRenderer() ErrorRenderer {
+	return minimalErrorRenderer{}
+}
+
+func (minimalErrorRenderer) Render(_ context.Context, _ *http.Request, _ runtime.Marshaler, s *status.Status, _ int) (string, []byte, error) {
+	buf, err := json.Marshal(minimalErrorBody{Code: int32(s.Code()), Message: s.Message()})
+	return "application/json", buf, err
+}
+
+func (minimalErrorRenderer) RenderFallback(runtime.Marshaler) (string, []byte) {
+	return "application/json", []byte(`{"code":13,"message":"failed to marshal error message"}`)
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tikivn/tikit-go-kit/e"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// failingErrorRenderer fails to render, to check the fallback body.
+type failingErrorRenderer struct {
+	ErrorRenderer
+}
+
+func (failingErrorRenderer) Render(context.Context, *http.Request, runtime.Marshaler, *status.Status, int) (string, []byte, error) {
+	return "", nil, errors.New("marshal failed")
+}
+
+func TestHTTPErrorHandler_renderers(t *testing.T) {
+	err := e.Error(codes.NotFound, "order not found").WithReason("ORDER_NOT_FOUND", "order.tiki.vn", nil)
+	marshaler := &runtime.JSONPb{MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: true}}
+
+	tests := []struct {
+		name     string
+		renderer ErrorRenderer
+		code     int
+		body     string
+	}{
+		{"status", StatusErrorRenderer(), http.StatusNotFound, `{"code": 5, "message": "order not found", "details": [{
+			"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": "ORDER_NOT_FOUND", "domain": "order.tiki.vn", "metadata": {}
+		}]}`},
+		{"legacy", LegacyErrorRenderer(), http.StatusNotFound, `{"error": {"code": "ORDER_NOT_FOUND", "message": "order not found"}, "success": false}`},
+		{"minimal", MinimalErrorRenderer(), http.StatusNotFound, `{"code": 5, "message": "order not found"}`},
+		{"status fallback", failingErrorRenderer{StatusErrorRenderer()}, http.StatusInternalServerError, `{"code": 13, "message": "failed to marshal error message"}`},
+		{"legacy fallback", failingErrorRenderer{LegacyErrorRenderer()}, http.StatusInternalServerError, `{"error": {"code": "Internal", "message": "failed to marshal error message"}, "success": false}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			HTTPErrorHandler(tt.renderer)(r.Context(), nil, marshaler, w, r, err)
+
+			assert.Equal(t, tt.code, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.body, w.Body.String())
+		})
+	}
+}
+
+func TestWithErrorRenderer(t *testing.T) {
+	c := createConfig([]Option{
+		WithServiceServer(&testHealthServer{}),
+		WithErrorRenderer(LegacyErrorRenderer()),
+	})
+	s, conn := newTestBackend(t, c)
+	gw, err := newGatewayServer(c.Gateway, s, conn, c.ServiceServers)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	gw.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"error": {"code": "Unavailable", "message": "not ready"}, "success": false}`, w.Body.String())
+}
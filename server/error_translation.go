@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tikivn/tikit-go-kit/e"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var untranslatedErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "grpc_server_untranslated_errors_total",
+	Help: "Total number of handler errors no translation rule matched.",
+}, []string{"grpc_method"})
+
+func init() {
+	prometheus.MustRegister(untranslatedErrors)
+}
+
+// ErrorRule translates a handler error into a status, ok is false when the rule does not apply.
+type ErrorRule func(err error) (s *e.Status, ok bool)
+
+// ErrorIsRule translates errors matching target with errors.Is, e.g.
+//
+//	ErrorIsRule(sql.ErrNoRows, codes.NotFound, "not found")
+func ErrorIsRule(target error, code codes.Code, message string) ErrorRule {
+	return func(err error) (*e.Status, bool) {
+		if !errors.Is(err, target) {
+			return nil, false
+		}
+		return e.Error(code, message).WithCause(err), true
+	}
+}
+
+// ErrorAsRule translates errors matching the type of target with errors.As,
+// translate receives the matched error of that type, e.g.
+//
+//	ErrorAsRule((*ValidationError)(nil), func(err error) *e.Status {
+//		v := err.(*ValidationError)
+//		return e.Error(codes.InvalidArgument, "invalid request").WithFieldViolation(v.Field, v.Reason)
+//	})
+func ErrorAsRule(target error, translate func(err error) *e.Status) ErrorRule {
+	typ := reflect.TypeOf(target)
+	return func(err error) (*e.Status, bool) {
+		ptr := reflect.New(typ)
+		if !errors.As(err, ptr.Interface()) {
+			return nil, false
+		}
+		return translate(ptr.Elem().Interface().(error)).WithCause(err), true
+	}
+}
+
+// ContextErrorRules translates context cancellation and deadlines.
+func ContextErrorRules() []ErrorRule {
+	return []ErrorRule{
+		ErrorIsRule(context.Canceled, codes.Canceled, "request canceled"),
+		ErrorIsRule(context.DeadlineExceeded, codes.DeadlineExceeded, "deadline exceeded"),
+	}
+}
+
+// translateError keeps gRPC statuses and e.Status, also when wrapped, and applies the first matching rule
+// to other errors. Errors no rule matches are counted and become "bad request" like e.WrapError,
+// keeping the error as the cause.
+func translateError(fullMethod string, err error, rules []ErrorRule) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var s *e.Status
+	if errors.As(err, &s) {
+		return s
+	}
+
+	for _, rule := range rules {
+		if s, ok := rule(err); ok {
+			return s
+		}
+	}
+	untranslatedErrors.WithLabelValues(fullMethod).Inc()
+	return e.WrapError(err)
+}
+
+func errorTranslationRules(rules []ErrorRule) []ErrorRule {
+	return append(append([]ErrorRule(nil), rules...), ContextErrorRules()...)
+}
+
+// ErrorTranslationUnaryServerInterceptor translates handler errors into e.Status with the rules,
+// then ContextErrorRules, instead of letting gRPC answer codes.Unknown.
+// Chain it inside ErrorLogUnaryServerInterceptor so that the causes it keeps are logged,
+// WithErrorTranslation and WithErrorLogging do so.
+func ErrorTranslationUnaryServerInterceptor(rules ...ErrorRule) grpc.UnaryServerInterceptor {
+	rules = errorTranslationRules(rules)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, translateError(info.FullMethod, err, rules)
+		}
+		return resp, nil
+	}
+}
+
+// ErrorTranslationStreamServerInterceptor is the stream counterpart of ErrorTranslationUnaryServerInterceptor.
+func ErrorTranslationStreamServerInterceptor(rules ...ErrorRule) grpc.StreamServerInterceptor {
+	rules = errorTranslationRules(rules)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return translateError(info.FullMethod, err, rules)
+		}
+		return nil
+	}
+}
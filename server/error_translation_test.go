@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tikivn/tikit-go-kit/e"
+	"github.com/tikivn/tikit-go-kit/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testValidationError struct {
+	field string
+}
+
+func (v *testValidationError) Error() string {
+	return v.field + " is invalid"
+}
+
+func TestTranslateError(t *testing.T) {
+	rules := errorTranslationRules([]ErrorRule{
+		ErrorIsRule(sql.ErrNoRows, codes.NotFound, "not found"),
+		ErrorAsRule((*testValidationError)(nil), func(err error) *e.Status {
+			return e.Error(codes.InvalidArgument, "invalid request").WithFieldViolation(err.(*testValidationError).field, "invalid")
+		}),
+	})
+	orderNotFound := e.Error(codes.NotFound, "order not found")
+
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{"status", status.Error(codes.Unavailable, "busy"), codes.Unavailable, "busy"},
+		{"e status", orderNotFound, codes.NotFound, "order not found"},
+		{"wrapped e status", fmt.Errorf("get order: %w", orderNotFound), codes.NotFound, "order not found"},
+		{"errors.Is", fmt.Errorf("get order: %w", sql.ErrNoRows), codes.NotFound, "not found"},
+		{"errors.As", fmt.Errorf("create order: %w", &testValidationError{field: "phone"}), codes.InvalidArgument, "invalid request"},
+		{"canceled", context.Canceled, codes.Canceled, "request canceled"},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), codes.DeadlineExceeded, "deadline exceeded"},
+		{"untranslated", errors.New("boom"), codes.InvalidArgument, "bad request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(translateError("/test.Service/Method", tt.err, rules))
+			assert.Equal(t, tt.code, st.Code())
+			assert.Equal(t, tt.message, st.Message())
+		})
+	}
+
+	s := translateError("/test.Service/Method", &testValidationError{field: "phone"}, rules).(*e.Status)
+	require.Len(t, s.FieldViolations(), 1)
+	assert.Equal(t, "phone", s.FieldViolations()[0].Field)
+	assert.Error(t, s.Cause())
+}
+
+func TestErrorTranslationUnaryServerInterceptor(t *testing.T) {
+	c := createConfig([]Option{
+		WithServiceServer(&errorHealthServer{err: errors.New("boom")}),
+		WithErrorTranslation(),
+	})
+	_, conn := newTestBackend(t, c)
+
+	before := testutil.ToFloat64(untranslatedErrors.WithLabelValues("/pb.HealthService/Liveness"))
+	_, err := pb.NewHealthServiceClient(conn).Liveness(context.Background(), &pb.LivenessRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, before+1, testutil.ToFloat64(untranslatedErrors.WithLabelValues("/pb.HealthService/Liveness")))
+}
@@ -53,8 +53,32 @@ type gatewayConfig struct {
 	ServerMiddlewares []HTTPServerMiddleware
 	ServerHandlers    []HTTPServerHandler
 	muxPaths          []string
+	backendHandlers   []backendHandler
+	muxMiddlewares    []muxMiddleware
+	pathPrefix        string
+	versions          []gatewayVersion
+	versionHeader     string
+
+	clientUnaryInterceptors  []grpc.UnaryClientInterceptor
+	clientStreamInterceptors []grpc.StreamClientInterceptor
 }
 
+// dialOptions returns the options of the connection the gateway calls the gRPC server through.
+func (c *gatewayConfig) dialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(c.clientUnaryInterceptors...),
+		grpc.WithChainStreamInterceptor(c.clientStreamInterceptors...),
+	}
+}
+
+// backendHandler creates an HTTPServerHandler which needs the in-process gRPC server
+// and the connection used by the gateway.
+type backendHandler func(*grpc.Server, *grpc.ClientConn) HTTPServerHandler
+
+// muxMiddleware creates an HTTPServerMiddleware which answers errors through the error handler of the mux.
+// They run after ServerMiddlewares, right before the mux.
+type muxMiddleware func(*runtime.ServeMux) HTTPServerMiddleware
+
 func createDefaultGatewayConfig() *gatewayConfig {
 	config := &gatewayConfig{
 		Addr: Listen{
@@ -63,9 +87,13 @@ func createDefaultGatewayConfig() *gatewayConfig {
 		},
 		MuxOptions: []runtime.ServeMuxOption{
 			gatewayopt.ProtoJSONMarshaler(),
-			runtime.WithErrorHandler(runtime.DefaultHTTPErrorHandler),
+			gatewayopt.ResponseControl(),
+			runtime.WithErrorHandler(DefaultHTTPErrorHandler),
 			runtime.WithRoutingErrorHandler(DefaultRoutingErrorHandler),
 		},
+		ServerMiddlewares: []HTTPServerMiddleware{
+			gatewayopt.ResponseControlMiddleware,
+		},
 		ServerHandlers: []HTTPServerHandler{
 			PrometheusHandler,
 			PprofHandler,
@@ -75,14 +103,34 @@ func createDefaultGatewayConfig() *gatewayConfig {
 	return config
 }
 
-func newGatewayServer(c *gatewayConfig, conn *grpc.ClientConn, servers []ServiceServer) (*gatewayServer, error) {
+func newGatewayServer(c *gatewayConfig, grpcSvr *grpc.Server, conn *grpc.ClientConn, servers []ServiceServer) (*gatewayServer, error) {
 	// init mux
 	mux := runtime.NewServeMux(c.MuxOptions...)
 
 	var handler http.Handler = mux
 
+	versioned := map[ServiceServer]bool{}
+	if len(c.versions) > 0 {
+		router := &versionRouter{header: c.versionHeader, muxes: map[string]http.Handler{}, fallback: mux}
+		for _, v := range c.versions {
+			vmux := runtime.NewServeMux(c.MuxOptions...)
+			for _, sv := range v.servers {
+				if err := sv.RegisterWithMuxServer(context.Background(), vmux, conn); err != nil {
+					return nil, fmt.Errorf("failed to register handler of version %s. %w", v.name, err)
+				}
+				versioned[sv] = true
+			}
+			router.muxes[v.name] = vmux
+		}
+		handler = router
+	}
+
 	//handler = otelhttp.NewHandler(handler, "")
 
+	for i := len(c.muxMiddlewares) - 1; i >= 0; i-- {
+		handler = c.muxMiddlewares[i](mux)(handler)
+	}
+
 	for i := len(c.ServerMiddlewares) - 1; i >= 0; i-- {
 		handler = c.ServerMiddlewares[i](handler)
 	}
@@ -93,6 +141,10 @@ func newGatewayServer(c *gatewayConfig, conn *grpc.ClientConn, servers []Service
 		h(httpMux)
 	}
 
+	for _, h := range c.backendHandlers {
+		h(grpcSvr, conn)(httpMux)
+	}
+
 	for _, svr := range servers {
 		for _, p := range c.muxPaths {
 			httpMux.HandleFunc(p, svr.MuxHandlers)
@@ -100,9 +152,14 @@ func newGatewayServer(c *gatewayConfig, conn *grpc.ClientConn, servers []Service
 	}
 	httpMux.Handle("/", handler)
 
+	var root http.Handler = httpMux
+	if c.pathPrefix != "" {
+		root = stripPathPrefix(c.pathPrefix, httpMux)
+	}
+
 	svr := &http.Server{
 		Addr:    c.Addr.String(),
-		Handler: httpMux,
+		Handler: root,
 	}
 
 	if cfg := c.ServerConfig; cfg != nil {
@@ -110,6 +167,9 @@ func newGatewayServer(c *gatewayConfig, conn *grpc.ClientConn, servers []Service
 	}
 
 	for _, sv := range servers {
+		if versioned[sv] {
+			continue
+		}
 		err := sv.RegisterWithMuxServer(context.Background(), mux, conn)
 		if err != nil {
 			return nil, fmt.Errorf("failed to register handler. %w", err)
@@ -0,0 +1,508 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/tikivn/tikit-go-kit/e"
+	"github.com/tikivn/tikit-go-kit/grpc/gatewayopt"
+	"github.com/tikivn/tikit-go-kit/l"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// GraphQLPath is the path GraphQLHandler serves on.
+const GraphQLPath = "/graphql"
+
+const (
+	defaultGraphQLMaxBodySize = 1 << 20
+	defaultGraphQLMaxDepth    = 10
+)
+
+type graphqlConfig struct {
+	maxBodySize int64
+	maxDepth    int
+}
+
+// GraphQLOption configures GraphQLHandler.
+type GraphQLOption func(*graphqlConfig)
+
+// WithGraphQLMaxBodySize limits the size of POST bodies, larger ones are answered with 413. 1MB by default.
+func WithGraphQLMaxBodySize(n int64) GraphQLOption {
+	return func(c *graphqlConfig) {
+		c.maxBodySize = n
+	}
+}
+
+// WithGraphQLMaxDepth limits the nesting of fields, fragments included, deeper queries
+// are answered with 400. 10 by default.
+func WithGraphQLMaxDepth(n int) GraphQLOption {
+	return func(c *graphqlConfig) {
+		c.maxDepth = n
+	}
+}
+
+// graphqlJSON carries map fields and well-known types in their protojson form.
+var graphqlJSON = graphql.NewScalar(graphql.ScalarConfig{
+	Name:         "JSON",
+	Description:  "Arbitrary JSON value, used for maps and well-known protobuf types.",
+	Serialize:    func(v interface{}) interface{} { return v },
+	ParseValue:   func(v interface{}) interface{} { return v },
+	ParseLiteral: graphqlLiteral,
+})
+
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphqlSchemaBuilder derives GraphQL types from proto descriptors.
+type graphqlSchemaBuilder struct {
+	conn      grpc.ClientConnInterface
+	objects   map[protoreflect.FullName]*graphql.Object
+	inputs    map[protoreflect.FullName]*graphql.InputObject
+	enums     map[protoreflect.FullName]*graphql.Enum
+	marshal   protojson.MarshalOptions
+	unmarshal protojson.UnmarshalOptions
+}
+
+// GraphQLHandler returns an HTTPServerHandler serving a GraphQL endpoint at GraphQLPath.
+// The schema is derived from the unary methods of services registered on s: methods bound to
+// an HTTP GET rule become queries, the others mutations. Fields are named <package>_<Service>_<Method>,
+// their arguments are the request fields and resolvers invoke the method through conn.
+// Mutations are only accepted over POST, GET requests carrying one are answered with 405.
+// Body size and query depth are limited, see WithGraphQLMaxBodySize and WithGraphQLMaxDepth.
+func GraphQLHandler(s *grpc.Server, conn grpc.ClientConnInterface, opts ...GraphQLOption) HTTPServerHandler {
+	c := &graphqlConfig{maxBodySize: defaultGraphQLMaxBodySize, maxDepth: defaultGraphQLMaxDepth}
+	for _, f := range opts {
+		f(c)
+	}
+	schema, err := newGraphQLSchema(s, conn)
+	if err != nil {
+		ll.Error("graphql: failed to build schema", l.Error(err))
+		return func(*http.ServeMux) {}
+	}
+
+	return func(httpMux *http.ServeMux) {
+		httpMux.HandleFunc(GraphQLPath, func(w http.ResponseWriter, r *http.Request) {
+			var req graphqlRequest
+			switch r.Method {
+			case http.MethodGet:
+				q := r.URL.Query()
+				req.Query = q.Get("query")
+				req.OperationName = q.Get("operationName")
+				if v := q.Get("variables"); v != "" {
+					if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+						return
+					}
+				}
+			case http.MethodPost:
+				body, err := ioutil.ReadAll(io.LimitReader(r.Body, c.maxBodySize+1))
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				if int64(len(body)) > c.maxBodySize {
+					http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				if err := json.Unmarshal(body, &req); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			default:
+				w.Header().Set("Allow", "GET, POST")
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			if doc, err := parser.Parse(parser.ParseParams{Source: req.Query}); err == nil {
+				// GET requests must not change state, mutations are only accepted over POST.
+				if op := graphqlOperation(doc, req.OperationName); r.Method == http.MethodGet && op != "" && op != ast.OperationTypeQuery {
+					w.Header().Set("Allow", "POST")
+					http.Error(w, op+" operations require POST", http.StatusMethodNotAllowed)
+					return
+				}
+				if depth := graphqlDepth(doc); depth > c.maxDepth {
+					http.Error(w, fmt.Sprintf("query depth %d exceeds %d", depth, c.maxDepth), http.StatusBadRequest)
+					return
+				}
+			}
+
+			result := graphql.Do(graphql.Params{
+				Schema:         schema,
+				RequestString:  req.Query,
+				VariableValues: req.Variables,
+				OperationName:  req.OperationName,
+				Context:        outgoingContextFromRequest(r),
+			})
+
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(result); err != nil {
+				ll.Info("graphql: failed to write response", l.Error(err))
+			}
+		})
+	}
+}
+
+// graphqlOperation returns the type of the operation named name in doc, or of its only operation when name is empty.
+// It is empty when the operation is not found, graphql.Do reports it.
+func graphqlOperation(doc *ast.Document, name string) string {
+	var ops []*ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			if name != "" && op.Name != nil && op.Name.Value == name {
+				return op.Operation
+			}
+			ops = append(ops, op)
+		}
+	}
+	if name == "" && len(ops) == 1 {
+		return ops[0].Operation
+	}
+	return ""
+}
+
+// graphqlDepth returns the deepest nesting of fields in doc, following fragment spreads
+// once per path so that cyclic fragments, rejected by graphql.Do, do not loop.
+func graphqlDepth(doc *ast.Document) int {
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, def := range doc.Definitions {
+		if f, ok := def.(*ast.FragmentDefinition); ok && f.Name != nil {
+			fragments[f.Name.Value] = f
+		}
+	}
+	var depth func(set *ast.SelectionSet, seen map[string]bool) int
+	depth = func(set *ast.SelectionSet, seen map[string]bool) int {
+		if set == nil {
+			return 0
+		}
+		deepest := 0
+		for _, sel := range set.Selections {
+			d := 0
+			switch sel := sel.(type) {
+			case *ast.Field:
+				d = 1 + depth(sel.SelectionSet, seen)
+			case *ast.InlineFragment:
+				d = depth(sel.SelectionSet, seen)
+			case *ast.FragmentSpread:
+				name := sel.Name.Value
+				if f, ok := fragments[name]; ok && !seen[name] {
+					seen[name] = true
+					d = depth(f.SelectionSet, seen)
+					delete(seen, name)
+				}
+			}
+			if d > deepest {
+				deepest = d
+			}
+		}
+		return deepest
+	}
+
+	deepest := 0
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			if d := depth(op.SelectionSet, map[string]bool{}); d > deepest {
+				deepest = d
+			}
+		}
+	}
+	return deepest
+}
+
+func newGraphQLSchema(s *grpc.Server, conn grpc.ClientConnInterface) (graphql.Schema, error) {
+	b := &graphqlSchemaBuilder{
+		conn:      conn,
+		objects:   make(map[protoreflect.FullName]*graphql.Object),
+		inputs:    make(map[protoreflect.FullName]*graphql.InputObject),
+		enums:     make(map[protoreflect.FullName]*graphql.Enum),
+		marshal:   protojson.MarshalOptions{EmitUnpopulated: true},
+		unmarshal: protojson.UnmarshalOptions{DiscardUnknown: true},
+	}
+
+	queries, mutations := graphql.Fields{}, graphql.Fields{}
+	for svcName, info := range s.GetServiceInfo() {
+		d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(svcName))
+		if err != nil {
+			ll.Warn("graphql: service descriptor not found", l.String("service", svcName))
+			continue
+		}
+		sd, ok := d.(protoreflect.ServiceDescriptor)
+		if !ok {
+			continue
+		}
+		for _, m := range info.Methods {
+			if m.IsClientStream || m.IsServerStream {
+				continue
+			}
+			md := sd.Methods().ByName(protoreflect.Name(m.Name))
+			if md == nil {
+				continue
+			}
+
+			name := graphqlName(md.FullName())
+			field := b.methodField(md, "/"+svcName+"/"+m.Name)
+			if rule, ok := proto.GetExtension(md.Options(), annotations.E_Http).(*annotations.HttpRule); ok && rule.GetGet() != "" {
+				queries[name] = field
+			} else {
+				mutations[name] = field
+			}
+		}
+	}
+
+	// A schema must declare at least one query.
+	if len(queries) == 0 {
+		queries["_empty"] = &graphql.Field{Type: graphql.Boolean}
+	}
+
+	cfg := graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: queries}),
+	}
+	if len(mutations) > 0 {
+		cfg.Mutation = graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: mutations})
+	}
+	return graphql.NewSchema(cfg)
+}
+
+func (b *graphqlSchemaBuilder) methodField(md protoreflect.MethodDescriptor, fullMethod string) *graphql.Field {
+	args := graphql.FieldConfigArgument{}
+	fields := md.Input().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		args[fd.JSONName()] = &graphql.ArgumentConfig{Type: b.inputType(fd)}
+	}
+
+	return &graphql.Field{
+		Type: b.object(md.Output()),
+		Args: args,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return b.resolve(p.Context, md, fullMethod, p.Args)
+		},
+	}
+}
+
+func (b *graphqlSchemaBuilder) resolve(ctx context.Context, md protoreflect.MethodDescriptor, fullMethod string, args map[string]interface{}) (interface{}, error) {
+	in := dynamicpb.NewMessage(md.Input())
+	if len(args) > 0 {
+		buf, err := json.Marshal(args)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.unmarshal.Unmarshal(buf, in); err != nil {
+			return nil, err
+		}
+	}
+
+	out := dynamicpb.NewMessage(md.Output())
+	if err := b.conn.Invoke(ctx, fullMethod, in, out); err != nil {
+		return nil, graphqlStatusError{e.Convert(err)}
+	}
+
+	buf, err := b.marshal.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(buf, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (b *graphqlSchemaBuilder) object(md protoreflect.MessageDescriptor) *graphql.Object {
+	if o, ok := b.objects[md.FullName()]; ok {
+		return o
+	}
+
+	o := graphql.NewObject(graphql.ObjectConfig{
+		Name: graphqlName(md.FullName()),
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			fields := graphql.Fields{}
+			fds := md.Fields()
+			for i := 0; i < fds.Len(); i++ {
+				fd := fds.Get(i)
+				fields[fd.JSONName()] = &graphql.Field{Type: b.outputType(fd)}
+			}
+			// Objects without fields are not valid GraphQL.
+			if len(fields) == 0 {
+				fields["_empty"] = &graphql.Field{Type: graphql.Boolean}
+			}
+			return fields
+		}),
+	})
+	b.objects[md.FullName()] = o
+	return o
+}
+
+func (b *graphqlSchemaBuilder) input(md protoreflect.MessageDescriptor) *graphql.InputObject {
+	if o, ok := b.inputs[md.FullName()]; ok {
+		return o
+	}
+
+	o := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: graphqlName(md.FullName()) + "Input",
+		Fields: graphql.InputObjectConfigFieldMapThunk(func() graphql.InputObjectConfigFieldMap {
+			fields := graphql.InputObjectConfigFieldMap{}
+			fds := md.Fields()
+			for i := 0; i < fds.Len(); i++ {
+				fd := fds.Get(i)
+				fields[fd.JSONName()] = &graphql.InputObjectFieldConfig{Type: b.inputType(fd)}
+			}
+			if len(fields) == 0 {
+				fields["_empty"] = &graphql.InputObjectFieldConfig{Type: graphql.Boolean}
+			}
+			return fields
+		}),
+	})
+	b.inputs[md.FullName()] = o
+	return o
+}
+
+func (b *graphqlSchemaBuilder) enum(ed protoreflect.EnumDescriptor) *graphql.Enum {
+	if e, ok := b.enums[ed.FullName()]; ok {
+		return e
+	}
+
+	values := graphql.EnumValueConfigMap{}
+	vds := ed.Values()
+	for i := 0; i < vds.Len(); i++ {
+		name := string(vds.Get(i).Name())
+		values[name] = &graphql.EnumValueConfig{Value: name}
+	}
+	e := graphql.NewEnum(graphql.EnumConfig{
+		Name:   graphqlName(ed.FullName()),
+		Values: values,
+	})
+	b.enums[ed.FullName()] = e
+	return e
+}
+
+func (b *graphqlSchemaBuilder) outputType(fd protoreflect.FieldDescriptor) graphql.Output {
+	var t graphql.Output
+	switch {
+	case fd.IsMap():
+		return graphqlJSON
+	case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+		if gatewayopt.IsWellKnownType(fd.Message()) {
+			t = graphqlJSON
+		} else {
+			t = b.object(fd.Message())
+		}
+	case fd.Kind() == protoreflect.EnumKind:
+		t = b.enum(fd.Enum())
+	default:
+		t = graphqlScalar(fd.Kind())
+	}
+	if fd.IsList() {
+		return graphql.NewList(t)
+	}
+	return t
+}
+
+func (b *graphqlSchemaBuilder) inputType(fd protoreflect.FieldDescriptor) graphql.Input {
+	var t graphql.Input
+	switch {
+	case fd.IsMap():
+		return graphqlJSON
+	case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+		if gatewayopt.IsWellKnownType(fd.Message()) {
+			t = graphqlJSON
+		} else {
+			t = b.input(fd.Message())
+		}
+	case fd.Kind() == protoreflect.EnumKind:
+		t = b.enum(fd.Enum())
+	default:
+		t = graphqlScalar(fd.Kind())
+	}
+	if fd.IsList() {
+		return graphql.NewList(t)
+	}
+	return t
+}
+
+// graphqlScalar maps a proto scalar kind to the GraphQL scalar matching its protojson form.
+func graphqlScalar(k protoreflect.Kind) *graphql.Scalar {
+	switch k {
+	case protoreflect.BoolKind:
+		return graphql.Boolean
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return graphql.Int
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.FloatKind, protoreflect.DoubleKind:
+		return graphql.Float
+	}
+	// 64-bit integers are strings in protojson, bytes are base64 strings.
+	return graphql.String
+}
+
+func graphqlName(n protoreflect.FullName) string {
+	return strings.ReplaceAll(string(n), ".", "_")
+}
+
+func graphqlLiteral(v ast.Value) interface{} {
+	switch v := v.(type) {
+	case *ast.StringValue:
+		return v.Value
+	case *ast.EnumValue:
+		return v.Value
+	case *ast.BooleanValue:
+		return v.Value
+	case *ast.IntValue:
+		if i, err := strconv.ParseInt(v.Value, 10, 64); err == nil {
+			return i
+		}
+		return v.Value
+	case *ast.FloatValue:
+		if f, err := strconv.ParseFloat(v.Value, 64); err == nil {
+			return f
+		}
+		return v.Value
+	case *ast.ListValue:
+		list := make([]interface{}, 0, len(v.Values))
+		for _, item := range v.Values {
+			list = append(list, graphqlLiteral(item))
+		}
+		return list
+	case *ast.ObjectValue:
+		obj := make(map[string]interface{}, len(v.Fields))
+		for _, f := range v.Fields {
+			obj[f.Name.Value] = graphqlLiteral(f.Value)
+		}
+		return obj
+	}
+	return nil
+}
+
+// graphqlStatusError exposes the gRPC code and HTTP status of a failed call in the GraphQL error extensions,
+// the HTTP status being the one the gateway answers with for the same error.
+type graphqlStatusError struct {
+	s *e.Status
+}
+
+func (g graphqlStatusError) Error() string {
+	return g.s.Message()
+}
+
+func (g graphqlStatusError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":   g.s.Code().String(),
+		"status": g.s.HTTPStatus,
+	}
+}
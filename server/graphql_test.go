@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tikivn/tikit-go-kit/e"
+	"google.golang.org/grpc/codes"
+)
+
+func TestGraphQLHandler(t *testing.T) {
+	c := createConfig([]Option{WithServiceServer(&testHealthServer{})})
+	s, conn := newTestBackend(t, c)
+
+	httpMux := http.NewServeMux()
+	GraphQLHandler(s, conn)(httpMux)
+
+	w := httptest.NewRecorder()
+	httpMux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, GraphQLPath, strings.NewReader(
+		`{"query":"{ live: pb_HealthService_Liveness { message } ready: pb_HealthService_Readiness { message } }"}`,
+	)))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"data": {"live": {"message": "ok"}, "ready": null},
+		"errors": [{
+			"message": "not ready",
+			"locations": [{"line": 1, "column": 47}],
+			"path": ["ready"],
+			"extensions": {"code": "Unavailable", "status": 503}
+		}]
+	}`, w.Body.String())
+}
+
+func TestGraphQLHandler_get(t *testing.T) {
+	c := createConfig([]Option{WithServiceServer(&testHealthServer{})})
+	s, conn := newTestBackend(t, c)
+
+	httpMux := http.NewServeMux()
+	GraphQLHandler(s, conn)(httpMux)
+
+	tests := []struct {
+		name  string
+		query string
+		op    string
+		want  int
+	}{
+		{"query", `{ pb_HealthService_Liveness { message } }`, "", http.StatusOK},
+		{"mutation", `mutation { pb_HealthService_Liveness { message } }`, "", http.StatusMethodNotAllowed},
+		{"named mutation", `query Q { pb_HealthService_Liveness { message } } mutation M { pb_HealthService_Liveness { message } }`, "M", http.StatusMethodNotAllowed},
+		{"named query", `query Q { pb_HealthService_Liveness { message } } mutation M { pb_HealthService_Liveness { message } }`, "Q", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := url.Values{"query": {tt.query}}
+			if tt.op != "" {
+				q.Set("operationName", tt.op)
+			}
+
+			w := httptest.NewRecorder()
+			httpMux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, GraphQLPath+"?"+q.Encode(), nil))
+
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusMethodNotAllowed {
+				assert.Equal(t, "POST", w.Header().Get("Allow"))
+			}
+		})
+	}
+}
+
+func TestGraphQLHandler_errorStatus(t *testing.T) {
+	err := e.Error(codes.FailedPrecondition, "order locked").SetHttpStatus(http.StatusLocked)
+	c := createConfig([]Option{WithServiceServer(&errorHealthServer{err: err})})
+	s, conn := newTestBackend(t, c)
+
+	httpMux := http.NewServeMux()
+	GraphQLHandler(s, conn)(httpMux)
+
+	w := httptest.NewRecorder()
+	httpMux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, GraphQLPath, strings.NewReader(
+		`{"query":"{ pb_HealthService_Liveness { message } }"}`,
+	)))
+
+	assert.Contains(t, w.Body.String(), `"extensions":{"code":"FailedPrecondition","status":423}`)
+}
+
+func TestGraphQLHandler_limits(t *testing.T) {
+	c := createConfig([]Option{WithServiceServer(&testHealthServer{})})
+	s, conn := newTestBackend(t, c)
+
+	httpMux := http.NewServeMux()
+	GraphQLHandler(s, conn, WithGraphQLMaxBodySize(128), WithGraphQLMaxDepth(2))(httpMux)
+	post := func(query string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(map[string]string{"query": query})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		httpMux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, GraphQLPath, bytes.NewReader(body)))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, post(`{ pb_HealthService_Liveness { message } }`).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(`{ pb_HealthService_Liveness { message } }`+strings.Repeat(" ", 128)).Code)
+
+	w := post(`{ a: pb_HealthService_Liveness { ...F } } fragment F on pb_LivenessResponse { message { x } }`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "query depth 3 exceeds 2")
+}
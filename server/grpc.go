@@ -17,8 +17,26 @@ type grpcConfig struct {
 	ServerStreamInterceptors []grpc.StreamServerInterceptor
 	ServerOption             []grpc.ServerOption
 	MaxConcurrentStreams     uint32
+
+	// errorUnaryInterceptors and errorStreamInterceptors are the kit error interceptors by errorStage,
+	// chained after the others in that order whatever the order of the options installing them.
+	errorUnaryInterceptors  [errorStages]grpc.UnaryServerInterceptor
+	errorStreamInterceptors [errorStages]grpc.StreamServerInterceptor
 }
 
+// errorStage orders the kit error interceptors, from the outermost one. Handler errors are translated
+// into e.Status first, then logged with their cause, counted, and sanitized last so that the logs and
+// metrics keep the original reason.
+type errorStage int
+
+const (
+	errorStageSanitize errorStage = iota
+	errorStageMetrics
+	errorStageLog
+	errorStageTranslate
+	errorStages
+)
+
 func createDefaultGrpcConfig() *grpcConfig {
 	// TODO: create interface to add option to logger
 	grpc_prometheus.EnableHandlingTimeHistogram()
@@ -59,10 +77,22 @@ func createDefaultGrpcConfig() *grpcConfig {
 }
 
 func (c *grpcConfig) ServerOptions() []grpc.ServerOption {
+	unaryInterceptors := append([]grpc.UnaryServerInterceptor(nil), c.ServerUnaryInterceptors...)
+	for _, i := range c.errorUnaryInterceptors {
+		if i != nil {
+			unaryInterceptors = append(unaryInterceptors, i)
+		}
+	}
+	streamInterceptors := append([]grpc.StreamServerInterceptor(nil), c.ServerStreamInterceptors...)
+	for _, i := range c.errorStreamInterceptors {
+		if i != nil {
+			streamInterceptors = append(streamInterceptors, i)
+		}
+	}
 	return append(
 		[]grpc.ServerOption{
-			grpc_middleware.WithUnaryServerChain(c.ServerUnaryInterceptors...),
-			grpc_middleware.WithStreamServerChain(c.ServerStreamInterceptors...),
+			grpc_middleware.WithUnaryServerChain(unaryInterceptors...),
+			grpc_middleware.WithStreamServerChain(streamInterceptors...),
 			grpc.MaxConcurrentStreams(c.MaxConcurrentStreams),
 		},
 		c.ServerOption...,
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/tikivn/tikit-go-kit/l"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// JSONRPCPath is the path JSONRPCHandler serves on.
+const JSONRPCPath = "/jsonrpc"
+
+const jsonrpcVersion = "2.0"
+
+// JSON-RPC 2.0 error codes, see https://www.jsonrpc.org/specification#error_object
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+	// JSONRPCServerError is the base of the implementation-defined server error range,
+	// other gRPC codes are reported as JSONRPCServerError - code.
+	JSONRPCServerError = -32000
+)
+
+type jsonrpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type jsonrpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *jsonrpcError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type jsonrpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+type jsonrpcMethod struct {
+	fullMethod string
+	desc       protoreflect.MethodDescriptor
+}
+
+type jsonrpcServer struct {
+	conn      grpc.ClientConnInterface
+	methods   map[string]jsonrpcMethod
+	marshal   protojson.MarshalOptions
+	unmarshal protojson.UnmarshalOptions
+}
+
+// JSONRPCHandler returns an HTTPServerHandler serving JSON-RPC 2.0 requests at JSONRPCPath.
+// Methods are named by their fully-qualified gRPC name, e.g. "pb.HealthService.Liveness",
+// resolved from the services registered on s and invoked through conn.
+// Only unary methods are exposed, params are decoded with protojson.
+func JSONRPCHandler(s *grpc.Server, conn grpc.ClientConnInterface) HTTPServerHandler {
+	js := &jsonrpcServer{
+		conn:      conn,
+		methods:   make(map[string]jsonrpcMethod),
+		marshal:   protojson.MarshalOptions{EmitUnpopulated: true},
+		unmarshal: protojson.UnmarshalOptions{DiscardUnknown: true},
+	}
+
+	for svcName, info := range s.GetServiceInfo() {
+		d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(svcName))
+		if err != nil {
+			ll.Warn("jsonrpc: service descriptor not found", l.String("service", svcName))
+			continue
+		}
+		sd, ok := d.(protoreflect.ServiceDescriptor)
+		if !ok {
+			continue
+		}
+		for _, m := range info.Methods {
+			if m.IsClientStream || m.IsServerStream {
+				continue
+			}
+			md := sd.Methods().ByName(protoreflect.Name(m.Name))
+			if md == nil {
+				continue
+			}
+			js.methods[svcName+"."+m.Name] = jsonrpcMethod{
+				fullMethod: "/" + svcName + "/" + m.Name,
+				desc:       md,
+			}
+		}
+	}
+
+	return func(httpMux *http.ServeMux) {
+		httpMux.Handle(JSONRPCPath, js)
+	}
+}
+
+func (js *jsonrpcServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		js.write(w, newJSONRPCError(nil, JSONRPCParseError, err.Error()))
+		return
+	}
+
+	ctx := outgoingContextFromRequest(r)
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			js.write(w, newJSONRPCError(nil, JSONRPCParseError, err.Error()))
+			return
+		}
+		if len(batch) == 0 {
+			js.write(w, newJSONRPCError(nil, JSONRPCInvalidRequest, "empty batch"))
+			return
+		}
+
+		responses := make([]*jsonrpcResponse, 0, len(batch))
+		for _, raw := range batch {
+			if resp := js.handle(ctx, raw); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		js.write(w, responses)
+		return
+	}
+
+	if resp := js.handle(ctx, body); resp != nil {
+		js.write(w, resp)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handle processes a single request object, it returns nil for notifications.
+func (js *jsonrpcServer) handle(ctx context.Context, raw json.RawMessage) *jsonrpcResponse {
+	var req jsonrpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return newJSONRPCError(nil, JSONRPCParseError, err.Error())
+		}
+		return newJSONRPCError(nil, JSONRPCInvalidRequest, err.Error())
+	}
+	if req.JSONRPC != jsonrpcVersion || req.Method == "" {
+		return newJSONRPCError(req.ID, JSONRPCInvalidRequest, "invalid request")
+	}
+
+	result, rpcErr := js.call(ctx, &req)
+	if req.ID == nil {
+		return nil
+	}
+	if rpcErr != nil {
+		return &jsonrpcResponse{JSONRPC: jsonrpcVersion, Error: rpcErr, ID: req.ID}
+	}
+	return &jsonrpcResponse{JSONRPC: jsonrpcVersion, Result: result, ID: req.ID}
+}
+
+func (js *jsonrpcServer) call(ctx context.Context, req *jsonrpcRequest) (json.RawMessage, *jsonrpcError) {
+	m, ok := js.methods[req.Method]
+	if !ok {
+		return nil, &jsonrpcError{Code: JSONRPCMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+	}
+
+	in := dynamicpb.NewMessage(m.desc.Input())
+	if params := bytes.TrimSpace(req.Params); len(params) > 0 && !bytes.Equal(params, []byte("null")) {
+		if params[0] != '{' {
+			return nil, &jsonrpcError{Code: JSONRPCInvalidParams, Message: "params must be an object"}
+		}
+		if err := js.unmarshal.Unmarshal(params, in); err != nil {
+			return nil, &jsonrpcError{Code: JSONRPCInvalidParams, Message: err.Error()}
+		}
+	}
+
+	out := dynamicpb.NewMessage(m.desc.Output())
+	if err := js.conn.Invoke(ctx, m.fullMethod, in, out); err != nil {
+		return nil, js.statusError(status.Convert(err))
+	}
+
+	result, err := js.marshal.Marshal(out)
+	if err != nil {
+		return nil, &jsonrpcError{Code: JSONRPCInternalError, Message: err.Error()}
+	}
+	return result, nil
+}
+
+func (js *jsonrpcServer) statusError(s *status.Status) *jsonrpcError {
+	rpcErr := &jsonrpcError{
+		Code:    JSONRPCCodeFromStatus(s.Code()),
+		Message: s.Message(),
+	}
+	if data, err := js.marshal.Marshal(s.Proto()); err == nil {
+		rpcErr.Data = data
+	}
+	return rpcErr
+}
+
+func (js *jsonrpcServer) write(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		ll.Info("jsonrpc: failed to write response", l.Error(err))
+	}
+}
+
+func newJSONRPCError(id json.RawMessage, code int, message string) *jsonrpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &jsonrpcResponse{
+		JSONRPC: jsonrpcVersion,
+		Error:   &jsonrpcError{Code: code, Message: message},
+		ID:      id,
+	}
+}
+
+// JSONRPCCodeFromStatus maps a gRPC code to a JSON-RPC 2.0 error code.
+func JSONRPCCodeFromStatus(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument:
+		return JSONRPCInvalidParams
+	case codes.Unimplemented:
+		return JSONRPCMethodNotFound
+	case codes.Internal:
+		return JSONRPCInternalError
+	}
+	return JSONRPCServerError - int(code)
+}
+
+// outgoingContextFromRequest forwards the authorization and Grpc-Metadata-* headers to the gRPC call.
+func outgoingContextFromRequest(r *http.Request) context.Context {
+	md := metadata.MD{}
+	for k, vs := range r.Header {
+		switch {
+		case k == "Authorization":
+			md.Append("authorization", vs...)
+		case strings.HasPrefix(k, runtime.MetadataHeaderPrefix):
+			md.Append(strings.TrimPrefix(k, runtime.MetadataHeaderPrefix), vs...)
+		}
+	}
+	return metadata.NewOutgoingContext(r.Context(), md)
+}
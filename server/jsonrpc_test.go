@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONRPCHandler(t *testing.T) {
+	c := createConfig([]Option{WithServiceServer(&testHealthServer{})})
+	s, conn := newTestBackend(t, c)
+
+	httpMux := http.NewServeMux()
+	JSONRPCHandler(s, conn)(httpMux)
+
+	call := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		httpMux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, JSONRPCPath, strings.NewReader(body)))
+		return w
+	}
+
+	t.Run("single", func(t *testing.T) {
+		w := call(`{"jsonrpc":"2.0","method":"pb.HealthService.Liveness","params":{},"id":1}`)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","result":{"message":"ok"},"id":1}`, w.Body.String())
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		w := call(`[
+			{"jsonrpc":"2.0","method":"pb.HealthService.Readiness","id":"a"},
+			{"jsonrpc":"2.0","method":"pb.HealthService.Liveness"},
+			{"jsonrpc":"2.0","method":"pb.Unknown.Method","id":"b"}
+		]`)
+
+		var resp []jsonrpcResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp, 2)
+		assert.Equal(t, JSONRPCServerError-14, resp[0].Error.Code)
+		assert.Equal(t, "not ready", resp[0].Error.Message)
+		assert.Equal(t, JSONRPCMethodNotFound, resp[1].Error.Code)
+	})
+
+	t.Run("notification", func(t *testing.T) {
+		w := call(`{"jsonrpc":"2.0","method":"pb.HealthService.Liveness"}`)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		w := call(`{"jsonrpc":`)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","error":{"code":-32700,"message":"unexpected end of JSON input"},"id":null}`, w.Body.String())
+	})
+}
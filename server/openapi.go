@@ -0,0 +1,513 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/tikivn/tikit-go-kit/docs"
+	"github.com/tikivn/tikit-go-kit/l"
+)
+
+const (
+	// OpenAPIPath serves the merged OpenAPI document.
+	OpenAPIPath = "/openapi.json"
+	// SwaggerUIPath serves the Swagger UI page for OpenAPIPath.
+	SwaggerUIPath = "/swagger/"
+
+	// swaggerUIAssetsPath serves the swagger-ui-dist files of WithSwaggerUIDist under SwaggerUIPath.
+	swaggerUIAssetsPath = SwaggerUIPath + "assets/"
+)
+
+// OpenAPIServiceServer is a ServiceServer which embeds its OpenAPI v2 documents, e.g.
+//
+//	//go:embed docs/*.swagger.json
+//	var docs embed.FS
+//
+//	func (s *Server) OpenAPIDocs() fs.FS { return docs }
+type OpenAPIServiceServer interface {
+	OpenAPIDocs() fs.FS
+}
+
+type openAPIConfig struct {
+	title     string
+	version   string
+	host      string
+	basePath  string
+	v3        bool
+	uiAssets  string
+	uiDist    fs.FS
+	extraDocs []fs.FS
+}
+
+// OpenAPIOption configures OpenAPIHandler.
+type OpenAPIOption func(*openAPIConfig)
+
+// WithOpenAPIInfo sets the title and version of the merged document.
+func WithOpenAPIInfo(title, version string) OpenAPIOption {
+	return func(c *openAPIConfig) {
+		c.title, c.version = title, version
+	}
+}
+
+// WithOpenAPIHost sets the host of the merged document, the host of the request when empty.
+func WithOpenAPIHost(host string) OpenAPIOption {
+	return func(c *openAPIConfig) {
+		c.host = host
+	}
+}
+
+// WithOpenAPIBasePath sets the base path of the merged document, e.g. the prefix the gateway is mounted at.
+func WithOpenAPIBasePath(basePath string) OpenAPIOption {
+	return func(c *openAPIConfig) {
+		c.basePath = basePath
+	}
+}
+
+// WithOpenAPI3 serves the merged document converted to OpenAPI 3.0.
+func WithOpenAPI3() OpenAPIOption {
+	return func(c *openAPIConfig) {
+		c.v3 = true
+	}
+}
+
+// WithSwaggerUIAssets sets where the Swagger UI page loads swagger-ui.css and swagger-ui-bundle.js from,
+// e.g. a static host serving the swagger-ui-dist package at a pinned version.
+func WithSwaggerUIAssets(baseURL string) OpenAPIOption {
+	return func(c *openAPIConfig) {
+		c.uiAssets = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithSwaggerUIDist serves the swagger-ui-dist files of dist, at least swagger-ui.css and swagger-ui-bundle.js,
+// for the Swagger UI page, e.g. embedded by the service:
+//
+//	//go:embed swagger-ui
+//	var swaggerUI embed.FS
+//
+//	dist, _ := fs.Sub(swaggerUI, "swagger-ui")
+//	server.WithOpenAPIHandler(server.WithSwaggerUIDist(dist))
+func WithSwaggerUIDist(dist fs.FS) OpenAPIOption {
+	return func(c *openAPIConfig) {
+		c.uiDist = dist
+	}
+}
+
+// WithOpenAPIDocs adds OpenAPI v2 documents of services which are not ServiceServers.
+func WithOpenAPIDocs(docs ...fs.FS) OpenAPIOption {
+	return func(c *openAPIConfig) {
+		c.extraDocs = append(c.extraDocs, docs...)
+	}
+}
+
+// OpenAPIHandler serves at OpenAPIPath the OpenAPI v2 documents embedded by the kit's HealthService
+// and by the servers implementing OpenAPIServiceServer, merged into one. A Swagger UI page is served
+// at SwaggerUIPath once its files are given with WithSwaggerUIDist or WithSwaggerUIAssets,
+// the kit does not ship them.
+func OpenAPIHandler(servers []ServiceServer, opts ...OpenAPIOption) HTTPServerHandler {
+	c := &openAPIConfig{
+		title:   "API",
+		version: "1.0",
+	}
+	for _, f := range opts {
+		f(c)
+	}
+	if c.uiDist != nil && c.uiAssets == "" {
+		c.uiAssets = strings.TrimSuffix(path.Join(c.basePath, swaggerUIAssetsPath), "/")
+	}
+
+	sources := []fs.FS{docs.FS}
+	for _, s := range servers {
+		if d, ok := s.(OpenAPIServiceServer); ok {
+			sources = append(sources, d.OpenAPIDocs())
+		}
+	}
+	sources = append(sources, c.extraDocs...)
+
+	doc, err := mergeOpenAPIDocs(sources, c)
+	if err != nil {
+		ll.Error("Failed to merge OpenAPI documents", l.Error(err))
+		doc = map[string]interface{}{"swagger": "2.0", "info": map[string]interface{}{"title": c.title, "version": c.version}}
+	}
+	if c.v3 {
+		doc = convertOpenAPI3(doc)
+	}
+
+	return func(mux *http.ServeMux) {
+		mux.HandleFunc(OpenAPIPath, func(w http.ResponseWriter, r *http.Request) {
+			serveOpenAPIDoc(w, r, doc, c)
+		})
+		if c.uiDist != nil {
+			mux.Handle(swaggerUIAssetsPath, http.StripPrefix(swaggerUIAssetsPath, http.FileServer(http.FS(c.uiDist))))
+		}
+		if c.uiAssets == "" {
+			return
+		}
+		mux.HandleFunc(SwaggerUIPath, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			err := swaggerUITemplate.Execute(w, map[string]string{
+				"Title":  c.title,
+				"Assets": c.uiAssets,
+				"URL":    path.Join(c.basePath, OpenAPIPath),
+			})
+			if err != nil {
+				ll.Info("Failed to write Swagger UI page", l.Error(err))
+			}
+		})
+	}
+}
+
+func serveOpenAPIDoc(w http.ResponseWriter, r *http.Request, doc map[string]interface{}, c *openAPIConfig) {
+	host := c.host
+	if host == "" {
+		host = r.Host
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	out := make(map[string]interface{}, len(doc)+2)
+	for k, v := range doc {
+		out[k] = v
+	}
+	if c.v3 {
+		out["servers"] = []interface{}{map[string]interface{}{"url": scheme + "://" + host + c.basePath}}
+	} else {
+		out["host"] = host
+		out["schemes"] = []interface{}{scheme}
+		if c.basePath != "" {
+			out["basePath"] = c.basePath
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		ll.Info("Failed to write OpenAPI document", l.Error(err))
+	}
+}
+
+// mergeOpenAPIDocs merges the paths, definitions, tags and security definitions of every
+// *.json document, the first declaration of a path operation or definition wins.
+func mergeOpenAPIDocs(sources []fs.FS, c *openAPIConfig) (map[string]interface{}, error) {
+	paths := map[string]interface{}{}
+	definitions := map[string]interface{}{}
+	security := map[string]interface{}{}
+	var tags []interface{}
+	tagNames := map[string]bool{}
+	consumes, produces := map[string]bool{}, map[string]bool{}
+
+	for _, src := range sources {
+		err := fs.WalkDir(src, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(name, ".json") {
+				return err
+			}
+			b, err := fs.ReadFile(src, name)
+			if err != nil {
+				return err
+			}
+			var doc map[string]interface{}
+			if err := json.Unmarshal(b, &doc); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if v, _ := doc["swagger"].(string); v != "2.0" {
+				return fmt.Errorf("%s: not an OpenAPI v2 document", name)
+			}
+
+			for p, item := range asObject(doc["paths"]) {
+				merged := asObject(paths[p])
+				if merged == nil {
+					merged = map[string]interface{}{}
+					paths[p] = merged
+				}
+				for method, op := range asObject(item) {
+					if _, ok := merged[method]; ok {
+						ll.Warn("Duplicated OpenAPI operation", l.String("path", p), l.String("method", method), l.String("doc", name))
+						continue
+					}
+					merged[method] = op
+				}
+			}
+			mergeObject(definitions, asObject(doc["definitions"]))
+			mergeObject(security, asObject(doc["securityDefinitions"]))
+			for _, t := range asArray(doc["tags"]) {
+				if n, _ := asObject(t)["name"].(string); n != "" && !tagNames[n] {
+					tagNames[n] = true
+					tags = append(tags, t)
+				}
+			}
+			for _, v := range asArray(doc["consumes"]) {
+				consumes[fmt.Sprint(v)] = true
+			}
+			for _, v := range asArray(doc["produces"]) {
+				produces[fmt.Sprint(v)] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	doc := map[string]interface{}{
+		"swagger":     "2.0",
+		"info":        map[string]interface{}{"title": c.title, "version": c.version},
+		"consumes":    sortedSet(consumes),
+		"produces":    sortedSet(produces),
+		"paths":       paths,
+		"definitions": definitions,
+	}
+	if len(tags) > 0 {
+		doc["tags"] = tags
+	}
+	if len(security) > 0 {
+		doc["securityDefinitions"] = security
+	}
+	return doc, nil
+}
+
+// convertOpenAPI3 converts an OpenAPI v2 document to OpenAPI 3.0, servers are set per request.
+func convertOpenAPI3(v2 map[string]interface{}) map[string]interface{} {
+	consumes := stringsOf(v2["consumes"], "application/json")
+	produces := stringsOf(v2["produces"], "application/json")
+
+	paths := map[string]interface{}{}
+	for p, item := range asObject(v2["paths"]) {
+		ops := map[string]interface{}{}
+		for method, op := range asObject(item) {
+			ops[method] = convertOpenAPI3Operation(asObject(op), consumes, produces)
+		}
+		paths[p] = ops
+	}
+
+	components := map[string]interface{}{"schemas": asObject(v2["definitions"])}
+	if sec := asObject(v2["securityDefinitions"]); len(sec) > 0 {
+		schemes := map[string]interface{}{}
+		for name, s := range sec {
+			schemes[name] = convertOpenAPI3Security(asObject(s))
+		}
+		components["securitySchemes"] = schemes
+	}
+
+	doc := map[string]interface{}{
+		"openapi":    "3.0.3",
+		"info":       v2["info"],
+		"paths":      paths,
+		"components": components,
+	}
+	if tags, ok := v2["tags"]; ok {
+		doc["tags"] = tags
+	}
+	if sec, ok := v2["security"]; ok {
+		doc["security"] = sec
+	}
+	return rewriteRefs(doc).(map[string]interface{})
+}
+
+func convertOpenAPI3Operation(op map[string]interface{}, consumes, produces []string) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range op {
+		switch k {
+		case "parameters", "responses", "consumes", "produces":
+		default:
+			out[k] = v
+		}
+	}
+	consumes = stringsOf(op["consumes"], consumes...)
+	produces = stringsOf(op["produces"], produces...)
+
+	var params []interface{}
+	form := map[string]interface{}{}
+	var formRequired []interface{}
+	for _, p := range asArray(op["parameters"]) {
+		param := asObject(p)
+		switch param["in"] {
+		case "body":
+			content := map[string]interface{}{}
+			for _, mt := range consumes {
+				content[mt] = map[string]interface{}{"schema": param["schema"]}
+			}
+			body := map[string]interface{}{"content": content}
+			if req, ok := param["required"]; ok {
+				body["required"] = req
+			}
+			if desc, ok := param["description"]; ok {
+				body["description"] = desc
+			}
+			out["requestBody"] = body
+		case "formData":
+			form[fmt.Sprint(param["name"])] = parameterSchema(param)
+			if req, _ := param["required"].(bool); req {
+				formRequired = append(formRequired, param["name"])
+			}
+		default:
+			converted := map[string]interface{}{"schema": parameterSchema(param)}
+			for _, k := range []string{"name", "in", "description", "required"} {
+				if v, ok := param[k]; ok {
+					converted[k] = v
+				}
+			}
+			params = append(params, converted)
+		}
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+	if len(form) > 0 {
+		schema := map[string]interface{}{"type": "object", "properties": form}
+		if len(formRequired) > 0 {
+			schema["required"] = formRequired
+		}
+		out["requestBody"] = map[string]interface{}{"content": map[string]interface{}{
+			"application/x-www-form-urlencoded": map[string]interface{}{"schema": schema},
+		}}
+	}
+
+	responses := map[string]interface{}{}
+	for code, r := range asObject(op["responses"]) {
+		resp := asObject(r)
+		converted := map[string]interface{}{"description": resp["description"]}
+		if schema, ok := resp["schema"]; ok {
+			content := map[string]interface{}{}
+			for _, mt := range produces {
+				content[mt] = map[string]interface{}{"schema": schema}
+			}
+			converted["content"] = content
+		}
+		if h, ok := resp["headers"]; ok {
+			headers := map[string]interface{}{}
+			for name, hv := range asObject(h) {
+				header := asObject(hv)
+				headers[name] = map[string]interface{}{"description": header["description"], "schema": parameterSchema(header)}
+			}
+			converted["headers"] = headers
+		}
+		responses[code] = converted
+	}
+	out["responses"] = responses
+	return out
+}
+
+// parameterSchema moves the type keywords of a v2 non-body parameter into a schema.
+func parameterSchema(param map[string]interface{}) map[string]interface{} {
+	schema := map[string]interface{}{}
+	for _, k := range []string{"type", "format", "items", "enum", "default", "pattern", "minimum", "maximum", "maxLength", "minLength"} {
+		if v, ok := param[k]; ok {
+			schema[k] = v
+		}
+	}
+	if schema["type"] == "file" {
+		schema["type"], schema["format"] = "string", "binary"
+	}
+	return schema
+}
+
+func convertOpenAPI3Security(s map[string]interface{}) map[string]interface{} {
+	switch s["type"] {
+	case "basic":
+		return map[string]interface{}{"type": "http", "scheme": "basic", "description": s["description"]}
+	case "oauth2":
+		flow := map[string]interface{}{"scopes": s["scopes"]}
+		if u, ok := s["authorizationUrl"]; ok {
+			flow["authorizationUrl"] = u
+		}
+		if u, ok := s["tokenUrl"]; ok {
+			flow["tokenUrl"] = u
+		}
+		name := map[interface{}]string{
+			"implicit":    "implicit",
+			"password":    "password",
+			"application": "clientCredentials",
+			"accessCode":  "authorizationCode",
+		}[s["flow"]]
+		return map[string]interface{}{"type": "oauth2", "flows": map[string]interface{}{name: flow}}
+	}
+	return s
+}
+
+// rewriteRefs points the "#/definitions/" references at "#/components/schemas/".
+func rewriteRefs(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			if ref, ok := e.(string); ok && k == "$ref" {
+				out[k] = strings.Replace(ref, "#/definitions/", "#/components/schemas/", 1)
+				continue
+			}
+			out[k] = rewriteRefs(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = rewriteRefs(e)
+		}
+		return out
+	}
+	return v
+}
+
+func asObject(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func asArray(v interface{}) []interface{} {
+	a, _ := v.([]interface{})
+	return a
+}
+
+func mergeObject(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if _, ok := dst[k]; !ok {
+			dst[k] = v
+		}
+	}
+}
+
+func sortedSet(set map[string]bool) []interface{} {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]interface{}, len(keys))
+	for i, k := range keys {
+		out[i] = k
+	}
+	return out
+}
+
+func stringsOf(v interface{}, fallback ...string) []string {
+	var out []string
+	for _, e := range asArray(v) {
+		out = append(out, fmt.Sprint(e))
+	}
+	if len(out) == 0 {
+		return fallback
+	}
+	return out
+}
+
+var swaggerUITemplate = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="{{.Assets}}/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="{{.Assets}}/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "{{.URL}}", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`))
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIHandler(t *testing.T) {
+	get := func(mux *http.ServeMux, path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Host = "api.tiki.vn"
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	mux := http.NewServeMux()
+	OpenAPIHandler([]ServiceServer{&testOrderServer{}}, WithOpenAPIBasePath("/api"), WithSwaggerUIAssets("https://cdn.tiki.vn/swagger-ui"))(mux)
+
+	var doc map[string]interface{}
+	w := get(mux, OpenAPIPath)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "api.tiki.vn", doc["host"])
+	assert.Equal(t, "/api", doc["basePath"])
+	assert.Contains(t, doc["paths"], "/health")
+	assert.Contains(t, doc["paths"], "/v1/orders/{id}")
+	assert.Contains(t, doc["definitions"], "pbOrder")
+	assert.Len(t, doc["tags"], 2)
+
+	w = get(mux, SwaggerUIPath)
+	assert.Contains(t, w.Body.String(), "swagger-ui-bundle.js")
+	assert.Contains(t, w.Body.String(), `\/api\/openapi.json`)
+
+	mux = http.NewServeMux()
+	OpenAPIHandler([]ServiceServer{&testOrderServer{}}, WithOpenAPI3(), WithOpenAPIHost("tiki.vn"))(mux)
+	doc = nil
+	require.NoError(t, json.Unmarshal(get(mux, OpenAPIPath).Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"url": "http://tiki.vn"}}, doc["servers"])
+	op := doc["paths"].(map[string]interface{})["/v1/orders/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"name": "id", "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"}}, op["parameters"].([]interface{})[0])
+	schema := op["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"]
+	assert.Equal(t, map[string]interface{}{"$ref": "#/components/schemas/pbOrder"}, schema)
+}
+
+func TestOpenAPIHandler_swaggerUIAssets(t *testing.T) {
+	get := func(mux *http.ServeMux, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	mux := http.NewServeMux()
+	OpenAPIHandler(nil, WithOpenAPIBasePath("/api"), WithSwaggerUIDist(fstest.MapFS{
+		"swagger-ui.css":       {Data: []byte("body{}")},
+		"swagger-ui-bundle.js": {Data: []byte("var SwaggerUIBundle")},
+	}))(mux)
+	assert.Contains(t, get(mux, SwaggerUIPath).Body.String(), `src="/api/swagger/assets/swagger-ui-bundle.js"`)
+	w := get(mux, SwaggerUIPath+"assets/swagger-ui-bundle.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "var SwaggerUIBundle", w.Body.String())
+
+	mux = http.NewServeMux()
+	OpenAPIHandler(nil, WithSwaggerUIAssets("https://cdn.tiki.vn/swagger-ui"))(mux)
+	assert.Contains(t, get(mux, SwaggerUIPath).Body.String(), `src="https://cdn.tiki.vn/swagger-ui/swagger-ui-bundle.js"`)
+
+	// without files the page is not served
+	mux = http.NewServeMux()
+	OpenAPIHandler(nil)(mux)
+	assert.Equal(t, http.StatusNotFound, get(mux, SwaggerUIPath).Code)
+	assert.Equal(t, http.StatusOK, get(mux, OpenAPIPath).Code)
+}
@@ -1,9 +1,12 @@
 package server
 
 import (
+	"net/http"
 	"os"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/tikivn/tikit-go-kit/e"
+	"github.com/tikivn/tikit-go-kit/grpc/gatewayopt"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/grpclog"
 )
@@ -76,6 +79,141 @@ func WithMuxHandlerPaths(paths []string) Option {
 	}
 }
 
+// WithJSONRPCHandler returns an Option that serves the registered unary gRPC methods as JSON-RPC 2.0 at JSONRPCPath.
+func WithJSONRPCHandler() Option {
+	return func(c *Config) {
+		c.Gateway.backendHandlers = append(c.Gateway.backendHandlers, func(s *grpc.Server, conn *grpc.ClientConn) HTTPServerHandler {
+			return JSONRPCHandler(s, conn)
+		})
+	}
+}
+
+// WithGraphQLHandler returns an Option that serves a GraphQL schema derived from the registered unary gRPC methods at GraphQLPath.
+func WithGraphQLHandler(opts ...GraphQLOption) Option {
+	return func(c *Config) {
+		c.Gateway.backendHandlers = append(c.Gateway.backendHandlers, func(s *grpc.Server, conn *grpc.ClientConn) HTTPServerHandler {
+			return GraphQLHandler(s, conn, opts...)
+		})
+	}
+}
+
+// WithContentNegotiation returns an Option that chooses the gateway response encoding from the Accept header
+// among the offered marshalers, gatewayopt.DefaultMarshalerOffers when none are given.
+// Requests accepting none of them are answered with 406 Not Acceptable.
+func WithContentNegotiation(offers ...gatewayopt.MarshalerOffer) Option {
+	if len(offers) == 0 {
+		offers = gatewayopt.DefaultMarshalerOffers()
+	}
+	mimes := make([]string, 0, len(offers))
+	for _, o := range offers {
+		mimes = append(mimes, o.MIME)
+	}
+	return func(c *Config) {
+		c.Gateway.MuxOptions = append(c.Gateway.MuxOptions, gatewayopt.NegotiatedMarshalers(offers...))
+		c.Gateway.muxMiddlewares = append(c.Gateway.muxMiddlewares, func(mux *runtime.ServeMux) HTTPServerMiddleware {
+			return gatewayopt.ContentNegotiationMiddleware(mux, mimes...)
+		})
+	}
+}
+
+// WithFieldMask returns an Option that prunes gateway responses to the FieldMask of the `fields` query parameter
+// or the X-Fields header, see gatewayopt.FieldMaskMiddleware. Invalid masks are answered with 400
+// before the method is called.
+func WithFieldMask(opts ...gatewayopt.FieldMaskOption) Option {
+	return func(c *Config) {
+		c.Gateway.ServerMiddlewares = append(c.Gateway.ServerMiddlewares, gatewayopt.FieldMaskMiddleware(opts...))
+		c.Gateway.MuxOptions = append(c.Gateway.MuxOptions, gatewayopt.FieldMaskResponse())
+		c.Gateway.clientUnaryInterceptors = append(c.Gateway.clientUnaryInterceptors, gatewayopt.FieldMaskUnaryClientInterceptor())
+		c.Gateway.clientStreamInterceptors = append(c.Gateway.clientStreamInterceptors, gatewayopt.FieldMaskStreamClientInterceptor())
+	}
+}
+
+// WithMultipartForm returns an Option that accepts multipart/form-data requests on the gateway,
+// installing gatewayopt.MultipartFormDataMarshaler with the MultipartMiddleware it requires.
+func WithMultipartForm(opts ...gatewayopt.MultipartOption) Option {
+	return func(c *Config) {
+		c.Gateway.MuxOptions = append(c.Gateway.MuxOptions, gatewayopt.MultipartFormDataMarshaler(opts...))
+		c.Gateway.ServerMiddlewares = append(c.Gateway.ServerMiddlewares, gatewayopt.MultipartMiddleware)
+	}
+}
+
+// WithHTTPBodyResponse returns an Option that applies the headers set with gatewayopt.SetContentDisposition,
+// SetCacheControl and SetContentLength, and serves Range requests on google.api.HttpBody responses.
+func WithHTTPBodyResponse() Option {
+	return func(c *Config) {
+		c.Gateway.MuxOptions = append(c.Gateway.MuxOptions, gatewayopt.HTTPBodyResponse())
+		c.Gateway.ServerMiddlewares = append(c.Gateway.ServerMiddlewares, gatewayopt.HTTPBodyRangeMiddleware)
+	}
+}
+
+// WithWebhookSignature returns an Option that verifies the HMAC signature of partner callbacks
+// on the configured paths before they reach the gateway.
+func WithWebhookSignature(configs ...WebhookSignatureConfig) Option {
+	return func(c *Config) {
+		c.Gateway.muxMiddlewares = append(c.Gateway.muxMiddlewares, func(mux *runtime.ServeMux) HTTPServerMiddleware {
+			return WebhookSignatureMiddleware(mux, configs...)
+		})
+	}
+}
+
+// WithOpenAPIHandler returns an Option that serves the OpenAPI documents of the ServiceServers, merged, at OpenAPIPath
+// along with a Swagger UI page at SwaggerUIPath given its files, see OpenAPIHandler.
+func WithOpenAPIHandler(opts ...OpenAPIOption) Option {
+	return func(c *Config) {
+		c.Gateway.ServerHandlers = append(c.Gateway.ServerHandlers, func(mux *http.ServeMux) {
+			// the servers are known once every option is applied
+			OpenAPIHandler(c.ServiceServers, opts...)(mux)
+		})
+	}
+}
+
+// WithErrorCatalog returns an Option that lists the errors of the catalogs at ErrorCatalogPath.
+func WithErrorCatalog(catalogs ...*e.Catalog) Option {
+	return func(c *Config) {
+		c.Gateway.ServerHandlers = append(c.Gateway.ServerHandlers, ErrorCatalogHandler(catalogs...))
+	}
+}
+
+// WithGatewayPathPrefix returns an Option that mounts the gateway under prefix, e.g. "/order-service".
+// The prefix is stripped before routing, requests without it are served as they are.
+func WithGatewayPathPrefix(prefix string) Option {
+	return func(c *Config) {
+		c.Gateway.pathPrefix = prefix
+	}
+}
+
+// WithGatewayVersion returns an Option that serves the ServiceServers as the API version, e.g. "v2".
+// Requests go to the version named by the header set with WithGatewayVersionHeader, or else by the
+// first path segment, other requests go to the servers added with WithServiceServer.
+// A server may be part of several versions, it is registered with gRPC once.
+func WithGatewayVersion(version string, servers ...ServiceServer) Option {
+	return func(c *Config) {
+		c.ServiceServers = appendServiceServers(c.ServiceServers, servers...)
+		c.Gateway.versions = append(c.Gateway.versions, gatewayVersion{name: version, servers: servers})
+	}
+}
+
+// WithGatewayVersionHeader returns an Option that routes requests by the API version in header, e.g. "X-API-Version".
+func WithGatewayVersionHeader(header string) Option {
+	return func(c *Config) {
+		c.Gateway.versionHeader = header
+	}
+}
+
+// WithDeprecationHeaders returns an Option that adds Deprecation and Sunset headers to the responses
+// of methods marked deprecated in their proto options.
+func WithDeprecationHeaders() Option {
+	return func(c *Config) {
+		c.Grpc.ServerUnaryInterceptors = append(c.Grpc.ServerUnaryInterceptors, DeprecationUnaryServerInterceptor())
+		c.Grpc.ServerStreamInterceptors = append(c.Grpc.ServerStreamInterceptors, DeprecationStreamServerInterceptor())
+	}
+}
+
+// WithGatewayTimeout returns an Option that sets the deadline of gateway requests, propagated to the gRPC handlers.
+func WithGatewayTimeout(cfg TimeoutConfig) Option {
+	return WithGatewayServerMiddlewares(TimeoutMiddleware(cfg))
+}
+
 ///-------------------------- GRPC options below--------------------------
 
 // WithGrpcAddr ...
@@ -95,6 +233,64 @@ func WithGrpcAddrListen(l Listen) Option {
 	}
 }
 
+// WithErrorRenderer returns an Option that renders gateway error bodies with the renderer,
+// e.g. LegacyErrorRenderer for older clients.
+func WithErrorRenderer(renderer ErrorRenderer) Option {
+	return func(c *Config) {
+		c.Gateway.MuxOptions = append(c.Gateway.MuxOptions, runtime.WithErrorHandler(HTTPErrorHandler(renderer)))
+	}
+}
+
+// WithProblemDetails returns an Option that renders gateway errors as RFC 7807 problem documents,
+// see ProblemHTTPErrorHandler.
+func WithProblemDetails(cfg ProblemConfig) Option {
+	return func(c *Config) {
+		c.Gateway.MuxOptions = append(c.Gateway.MuxOptions, runtime.WithErrorHandler(ProblemHTTPErrorHandler(cfg)))
+	}
+}
+
+// WithErrorLogging returns an Option that logs the cause chain and stack trace of e.Status errors
+// returned by handlers, which clients never receive. It logs the errors translated by WithErrorTranslation,
+// before WithErrorSanitization replaces them.
+func WithErrorLogging() Option {
+	return func(c *Config) {
+		c.Grpc.errorUnaryInterceptors[errorStageLog] = ErrorLogUnaryServerInterceptor()
+		c.Grpc.errorStreamInterceptors[errorStageLog] = ErrorLogStreamServerInterceptor()
+	}
+}
+
+// WithErrorTranslation returns an Option that translates handler errors into e.Status with the rules,
+// see ErrorTranslationUnaryServerInterceptor. The other kit error options see the translated errors.
+func WithErrorTranslation(rules ...ErrorRule) Option {
+	return func(c *Config) {
+		c.Grpc.errorUnaryInterceptors[errorStageTranslate] = ErrorTranslationUnaryServerInterceptor(rules...)
+		c.Grpc.errorStreamInterceptors[errorStageTranslate] = ErrorTranslationStreamServerInterceptor(rules...)
+	}
+}
+
+// WithErrorSanitization returns an Option that hides the messages of internal errors from callers,
+// see SanitizeUnaryServerInterceptor. Render gateway errors with SanitizingErrorRenderer to sanitize
+// the errors raised by the gateway too. It runs after the other kit error options, which see the original errors.
+func WithErrorSanitization(cfg SanitizeConfig) Option {
+	return func(c *Config) {
+		c.Grpc.errorUnaryInterceptors[errorStageSanitize] = SanitizeUnaryServerInterceptor(cfg)
+		c.Grpc.errorStreamInterceptors[errorStageSanitize] = SanitizeStreamServerInterceptor(cfg)
+	}
+}
+
+// WithErrorMetrics returns an Option that counts errors of the gRPC server and the gateway by method,
+// gRPC code, HTTP status and reason, see ErrorMetricsUnaryServerInterceptor and ErrorMetricsMiddleware.
+// The server and the gateway share the MaxReasons limit. Errors are counted once translated by
+// WithErrorTranslation and before WithErrorSanitization replaces them.
+func WithErrorMetrics(cfg ErrorMetricsConfig) Option {
+	return func(c *Config) {
+		m := newErrorMetrics(cfg)
+		c.Grpc.errorUnaryInterceptors[errorStageMetrics] = m.unaryServerInterceptor()
+		c.Grpc.errorStreamInterceptors[errorStageMetrics] = m.streamServerInterceptor()
+		c.Gateway.ServerMiddlewares = append(c.Gateway.ServerMiddlewares, m.middleware)
+	}
+}
+
 // WithGrpcServerUnaryInterceptors returns an Option that sets unary interceptor(s) for a gRPC server.
 func WithGrpcServerUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
 	return func(c *Config) {
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithFieldMask(t *testing.T) {
+	c := createConfig([]Option{WithServiceServer(&testHealthServer{}), WithFieldMask()})
+	s, conn := newTestBackend(t, c)
+	gw, err := newGatewayServer(c.Gateway, s, conn, c.ServiceServers)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	gw.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health?fields=message", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message": "ok"}`, w.Body.String())
+
+	// the handler would fail with Internal if it were called
+	c = createConfig([]Option{WithServiceServer(&errorHealthServer{err: status.Error(codes.Internal, "called")}), WithFieldMask()})
+	s, conn = newTestBackend(t, c)
+	gw, err = newGatewayServer(c.Gateway, s, conn, c.ServiceServers)
+	require.NoError(t, err)
+
+	w = httptest.NewRecorder()
+	gw.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health?fields=unknown", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `invalid field mask path \"unknown\"`)
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/tikivn/tikit-go-kit/e"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ProblemContentType is the media type of RFC 7807 problem documents.
+	ProblemContentType = "application/problem+json"
+	// DefaultRequestIDHeader holds the request ID used as the problem instance.
+	DefaultRequestIDHeader = "X-Request-Id"
+)
+
+// ProblemConfig configures ProblemHTTPErrorHandler.
+type ProblemConfig struct {
+	// TypeBaseURI prefixes the reason of an error to make the problem type,
+	// e.g. "https://developers.tiki.vn/errors/". The type is "about:blank" when empty or without reason.
+	TypeBaseURI string
+	// Routes answer every error with problem documents, by path prefix. Other requests
+	// get them when they accept application/problem+json and DefaultHTTPErrorHandler's format otherwise.
+	Routes []string
+	// RequestIDHeader is read from the request, then from the header metadata of the call, X-Request-Id by default.
+	RequestIDHeader string
+	// Renderer renders the errors of other requests, StatusErrorRenderer when nil.
+	Renderer ErrorRenderer
+}
+
+// Problem is an RFC 7807 problem document.
+type Problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	Code          string         `json:"code"`
+	InvalidParams []ProblemParam `json:"invalid-params,omitempty"`
+}
+
+// ProblemParam is a field violation of a problem.
+type ProblemParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// ProblemHTTPErrorHandler returns an error handler rendering statuses as RFC 7807 problem documents
+// on the configured routes or when requested by Accept. Header and trailer metadata are forwarded
+// like DefaultHTTPErrorHandler does.
+func ProblemHTTPErrorHandler(cfg ProblemConfig) runtime.ErrorHandlerFunc {
+	if cfg.RequestIDHeader == "" {
+		cfg.RequestIDHeader = DefaultRequestIDHeader
+	}
+	if cfg.Renderer == nil {
+		cfg.Renderer = StatusErrorRenderer()
+	}
+	problem := problemRenderer{cfg: cfg}
+	return func(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+		if !cfg.wantsProblem(r) {
+			renderHTTPError(ctx, cfg.Renderer, marshaler, w, r, err)
+			return
+		}
+		renderHTTPError(ctx, problem, marshaler, w, r, err)
+	}
+}
+
+// problemRenderer renders problem documents, whatever the marshaler.
+type problemRenderer struct {
+	cfg ProblemConfig
+}
+
+func (p problemRenderer) Render(ctx context.Context, r *http.Request, _ runtime.Marshaler, s *status.Status, httpStatus int) (string, []byte, error) {
+	buf, err := json.Marshal(p.cfg.problem(ctx, r, s, httpStatus))
+	return ProblemContentType, buf, err
+}
+
+func (p problemRenderer) RenderFallback(runtime.Marshaler) (string, []byte) {
+	return ProblemContentType, []byte(`{"type":"about:blank","title":"Internal Server Error","status":500}`)
+}
+
+func (cfg ProblemConfig) wantsProblem(r *http.Request) bool {
+	for _, prefix := range cfg.Routes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	for _, part := range strings.Split(strings.Join(r.Header.Values("Accept"), ","), ",") {
+		if mt, _, err := mime.ParseMediaType(part); err == nil && mt == ProblemContentType {
+			return true
+		}
+	}
+	return false
+}
+
+func (cfg ProblemConfig) problem(ctx context.Context, r *http.Request, s *status.Status, httpStatus int) *Problem {
+	es := e.Status{Err: s}
+	p := &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(httpStatus),
+		Status:   httpStatus,
+		Detail:   s.Message(),
+		Instance: cfg.requestID(ctx, r),
+		Code:     s.Code().String(),
+	}
+	if reason := es.Reason(); reason != "" && cfg.TypeBaseURI != "" {
+		p.Type = cfg.TypeBaseURI + reason
+	}
+	for _, v := range es.FieldViolations() {
+		p.InvalidParams = append(p.InvalidParams, ProblemParam{Name: v.Field, Reason: v.Description})
+	}
+	return p
+}
+
+func (cfg ProblemConfig) requestID(ctx context.Context, r *http.Request) string {
+	if id := r.Header.Get(cfg.RequestIDHeader); id != "" {
+		return id
+	}
+	if md, ok := runtime.ServerMetadataFromContext(ctx); ok {
+		if ids := md.HeaderMD.Get(cfg.RequestIDHeader); len(ids) > 0 {
+			return ids[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tikivn/tikit-go-kit/e"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestProblemHTTPErrorHandler(t *testing.T) {
+	err := e.Error(codes.InvalidArgument, "invalid order").
+		WithReason("ORDER_INVALID", "order.tiki.vn", nil).
+		WithFieldViolation("customer.phone", "invalid phone number").
+		SetHttpStatus(http.StatusUnprocessableEntity)
+	c := createConfig([]Option{
+		WithServiceServer(&errorHealthServer{err: err, header: metadata.Pairs("x-request-id", "req-1")}),
+		WithProblemDetails(ProblemConfig{TypeBaseURI: "https://developers.tiki.vn/errors/", Routes: []string{"/ready"}}),
+	})
+	s, conn := newTestBackend(t, c)
+	gw, gerr := newGatewayServer(c.Gateway, s, conn, c.ServiceServers)
+	require.NoError(t, gerr)
+
+	call := func(path, accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		gw.server.Handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := call("/health", "application/problem+json, application/json;q=0.5")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Grpc-Metadata-X-Request-Id"), "header metadata is filtered like DefaultHTTPErrorHandler does")
+	assert.JSONEq(t, `{
+		"type": "https://developers.tiki.vn/errors/ORDER_INVALID",
+		"title": "Unprocessable Entity",
+		"status": 422,
+		"detail": "invalid order",
+		"instance": "req-1",
+		"code": "InvalidArgument",
+		"invalid-params": [{"name": "customer.phone", "reason": "invalid phone number"}]
+	}`, w.Body.String())
+
+	w = call("/health", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	// problem routes, routing errors included
+	w = call("/ready", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"type": "about:blank", "title": "Service Unavailable", "status": 503, "detail": "not ready", "code": "Unavailable"}`, w.Body.String())
+
+	w = call("/missing", ProblemContentType)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+}
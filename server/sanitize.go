@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/tikivn/tikit-go-kit/e"
+	"github.com/tikivn/tikit-go-kit/l"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// SanitizedReason is the ErrorInfo reason of sanitized errors, the error ID is in its "error_id" metadata.
+	SanitizedReason = "INTERNAL_ERROR"
+	sanitizedDomain = "tikit-go-kit"
+
+	defaultSanitizedMessage = "internal error"
+)
+
+// SanitizeConfig configures the replacement of internal error messages.
+type SanitizeConfig struct {
+	// Codes are sanitized, Internal, Unknown and DataLoss when empty.
+	Codes []codes.Code
+	// AllowCodes and AllowReasons pass through unchanged, reasons as set by e.Status.WithReason.
+	AllowCodes   []codes.Code
+	AllowReasons []string
+	// Message replaces the original one, followed by the error ID, "internal error" by default.
+	Message string
+}
+
+func (cfg SanitizeConfig) withDefaults() SanitizeConfig {
+	if len(cfg.Codes) == 0 {
+		cfg.Codes = []codes.Code{codes.Internal, codes.Unknown, codes.DataLoss}
+	}
+	if cfg.Message == "" {
+		cfg.Message = defaultSanitizedMessage
+	}
+	return cfg
+}
+
+// sanitize returns the status sent in place of s, ok is false when s passes through.
+func (cfg SanitizeConfig) sanitize(method string, s *e.Status) (*e.Status, bool) {
+	if !containsCode(cfg.Codes, s.Code()) || containsCode(cfg.AllowCodes, s.Code()) {
+		return nil, false
+	}
+	if isSanitized(s) {
+		return nil, false
+	}
+	reason := s.Reason()
+	if containsString(cfg.AllowReasons, reason) {
+		return nil, false
+	}
+
+	id := newErrorID()
+	ll.Error("Error sanitized",
+		l.String("error_id", id),
+		l.String("method", method),
+		l.String("code", s.Code().String()),
+		l.String("reason", reason),
+		l.String("message", s.Message()),
+		l.String("cause", strings.Join(s.CauseChain(), " <- ")),
+		l.Strings("stack", s.StackTrace()),
+	)
+
+	sanitized := e.Error(s.Code(), cfg.Message+" (error ID: "+id+")").
+		WithReason(SanitizedReason, sanitizedDomain, map[string]string{"error_id": id})
+	sanitized.HTTPStatus = s.HTTPStatus
+	return sanitized, true
+}
+
+// isSanitized reports whether s was sanitized by the kit already, a handler reusing SanitizedReason
+// in another domain or without an error ID is sanitized again.
+func isSanitized(s *e.Status) bool {
+	info, ok := s.ErrorInfo()
+	return ok && info.Reason == SanitizedReason && info.Domain == sanitizedDomain && info.Metadata["error_id"] != ""
+}
+
+func (cfg SanitizeConfig) sanitizeError(method string, err error) error {
+	var s *e.Status
+	if !errors.As(err, &s) {
+		st, ok := status.FromError(err)
+		if !ok {
+			st = status.New(codes.Unknown, err.Error())
+		}
+		s = &e.Status{HTTPStatus: e.HTTPStatusFromCode(st.Code()), Err: st}
+	}
+	if sanitized, ok := cfg.sanitize(method, s); ok {
+		return sanitized
+	}
+	return err
+}
+
+func containsCode(values []codes.Code, code codes.Code) bool {
+	for _, c := range values {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func newErrorID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// SanitizeUnaryServerInterceptor replaces the message and details of Internal, Unknown and DataLoss errors,
+// or the configured codes, with a generic message and an error ID. The original error is logged with that ID.
+func SanitizeUnaryServerInterceptor(cfg SanitizeConfig) grpc.UnaryServerInterceptor {
+	cfg = cfg.withDefaults()
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, cfg.sanitizeError(info.FullMethod, err)
+		}
+		return resp, nil
+	}
+}
+
+// SanitizeStreamServerInterceptor is the stream counterpart of SanitizeUnaryServerInterceptor.
+func SanitizeStreamServerInterceptor(cfg SanitizeConfig) grpc.StreamServerInterceptor {
+	cfg = cfg.withDefaults()
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return cfg.sanitizeError(info.FullMethod, err)
+		}
+		return nil
+	}
+}
+
+type sanitizingErrorRenderer struct {
+	cfg  SanitizeConfig
+	next ErrorRenderer
+}
+
+// SanitizingErrorRenderer sanitizes gateway errors like SanitizeUnaryServerInterceptor before rendering them
+// with next, for errors raised by the gateway itself or backends without the interceptor.
+func SanitizingErrorRenderer(cfg SanitizeConfig, next ErrorRenderer) ErrorRenderer {
+	return sanitizingErrorRenderer{cfg: cfg.withDefaults(), next: next}
+}
+
+func (s sanitizingErrorRenderer) Render(ctx context.Context, r *http.Request, marshaler runtime.Marshaler, st *status.Status, httpStatus int) (string, []byte, error) {
+	if sanitized, ok := s.cfg.sanitize(r.Method+" "+r.URL.Path, &e.Status{HTTPStatus: httpStatus, Err: st}); ok {
+		st = sanitized.Err
+	}
+	return s.next.Render(ctx, r, marshaler, st, httpStatus)
+}
+
+func (s sanitizingErrorRenderer) RenderFallback(marshaler runtime.Marshaler) (string, []byte) {
+	return s.next.RenderFallback(marshaler)
+}
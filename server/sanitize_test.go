@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tikivn/tikit-go-kit/e"
+	"github.com/tikivn/tikit-go-kit/l"
+	"github.com/tikivn/tikit-go-kit/pb"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSanitizeUnaryServerInterceptor(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	defer func(logger l.Logger) { ll = logger }(ll)
+	ll = l.Logger{Logger: zap.New(core)}
+
+	leak := `pq: relation "orders" does not exist`
+	tests := []struct {
+		name      string
+		err       error
+		sanitized bool
+	}{
+		{"internal", e.Error(codes.Internal, leak), true},
+		{"plain error", errors.New(leak), true},
+		{"unknown", status.Error(codes.Unknown, leak), true},
+		{"client error", e.Error(codes.InvalidArgument, "invalid order"), false},
+		{"allowed code", status.Error(codes.DataLoss, "order archived"), false},
+		{"allowed reason", e.Error(codes.Internal, "payment gateway down").WithReason("PAYMENT_UNAVAILABLE", "order.tiki.vn", nil), false},
+		{"spoofed sanitized reason", e.Error(codes.Internal, leak).WithReason(SanitizedReason, "order.tiki.vn", nil), true},
+		{"sanitized without error ID", e.Error(codes.Internal, leak).WithReason(SanitizedReason, sanitizedDomain, nil), true},
+		{"already sanitized", e.Error(codes.Internal, "internal error (error ID: 1)").WithReason(SanitizedReason, sanitizedDomain, map[string]string{"error_id": "1"}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.TakeAll()
+			c := createConfig([]Option{
+				WithServiceServer(&errorHealthServer{err: tt.err}),
+				WithErrorSanitization(SanitizeConfig{AllowCodes: []codes.Code{codes.DataLoss}, AllowReasons: []string{"PAYMENT_UNAVAILABLE"}}),
+			})
+			_, conn := newTestBackend(t, c)
+
+			_, err := pb.NewHealthServiceClient(conn).Liveness(context.Background(), &pb.LivenessRequest{})
+			got := e.Status{Err: status.Convert(err)}
+			if !tt.sanitized {
+				assert.Equal(t, status.Convert(tt.err).Message(), got.Message())
+				assert.Zero(t, logs.Len())
+				return
+			}
+
+			assert.NotContains(t, got.Message(), "pq:")
+			assert.True(t, strings.HasPrefix(got.Message(), "internal error (error ID: "), got.Message())
+			info, ok := got.ErrorInfo()
+			require.True(t, ok)
+			assert.Equal(t, SanitizedReason, info.Reason)
+
+			require.Equal(t, 1, logs.Len())
+			fields := logs.All()[0].ContextMap()
+			assert.Equal(t, info.Metadata["error_id"], fields["error_id"])
+			assert.Equal(t, leak, fields["message"])
+		})
+	}
+}
+
+func TestSanitizingErrorRenderer(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	defer func(logger l.Logger) { ll = logger }(ll)
+	ll = l.Logger{Logger: zap.New(core)}
+
+	handler := HTTPErrorHandler(SanitizingErrorRenderer(SanitizeConfig{Message: "something went wrong"}, MinimalErrorRenderer()))
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	handler(r.Context(), nil, &runtime.JSONPb{}, w, r, status.Error(codes.Internal, "dial tcp 10.0.0.1:5432: connection refused"))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var body minimalErrorBody
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.True(t, strings.HasPrefix(body.Message, "something went wrong (error ID: "), body.Message)
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, "GET /orders", logs.All()[0].ContextMap()["method"])
+
+	// already sanitized errors keep their ID
+	sanitized := e.Error(codes.Internal, "internal error (error ID: 1)").WithReason(SanitizedReason, sanitizedDomain, map[string]string{"error_id": "1"})
+	w = httptest.NewRecorder()
+	handler(r.Context(), nil, &runtime.JSONPb{}, w, r, sanitized)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "internal error (error ID: 1)", body.Message)
+}
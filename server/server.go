@@ -35,17 +35,18 @@ func New(opts ...Option) (*Server, error) {
 	// 	return nil, fmt.Errorf("Faild to create grpc server. %w", err)
 	// }
 
-	conn, err := grpc.Dial(c.Grpc.Addr.String(), grpc.WithInsecure(),
-		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(1024*1024*50)),
-		grpc.WithChainUnaryInterceptor(),
-	)
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(1024 * 1024 * 50)),
+	}, c.Gateway.dialOptions()...)
+	conn, err := grpc.Dial(c.Grpc.Addr.String(), dialOpts...)
 
 	if err != nil {
 		return nil, fmt.Errorf("fail to dial gRPC server. %w", err)
 	}
 
 	ll.Info("Create gateway server")
-	gatewayServerHost, err := newGatewayServer(c.Gateway, conn, c.ServiceServers)
+	gatewayServerHost, err := newGatewayServer(c.Gateway, grpcServerHost.server, conn, c.ServiceServers)
 	if err != nil {
 		return nil, fmt.Errorf("fail to create gateway server. %w", err)
 	}
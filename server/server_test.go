@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"io/fs"
+	"net"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/tikivn/tikit-go-kit/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testHealthServer struct {
+	pb.UnimplementedHealthServiceServer
+}
+
+func (s *testHealthServer) RegisterWithGrpcServer(g *grpc.Server) {
+	pb.RegisterHealthServiceServer(g, s)
+}
+
+func (s *testHealthServer) RegisterWithMuxServer(ctx context.Context, mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	return pb.RegisterHealthServiceHandler(ctx, mux, conn)
+}
+
+func (s *testHealthServer) MuxHandlers(http.ResponseWriter, *http.Request) {}
+
+func (s *testHealthServer) Close(context.Context) {}
+
+func (s *testHealthServer) Liveness(context.Context, *pb.LivenessRequest) (*pb.LivenessResponse, error) {
+	return &pb.LivenessResponse{Message: "ok"}, nil
+}
+
+func (s *testHealthServer) Readiness(context.Context, *pb.ReadinessRequest) (*pb.ReadinessResponse, error) {
+	return nil, status.Error(codes.Unavailable, "not ready")
+}
+
+// errorHealthServer fails every call with the configured error, sending the header metadata.
+type errorHealthServer struct {
+	testHealthServer
+	err    error
+	header metadata.MD
+}
+
+func (s *errorHealthServer) RegisterWithGrpcServer(g *grpc.Server) {
+	pb.RegisterHealthServiceServer(g, s)
+}
+
+func (s *errorHealthServer) Liveness(ctx context.Context, _ *pb.LivenessRequest) (*pb.LivenessResponse, error) {
+	if s.header != nil {
+		_ = grpc.SetHeader(ctx, s.header)
+	}
+	return nil, s.err
+}
+
+// slowHealthServer answers Liveness once the deadline of the call has passed.
+type slowHealthServer struct {
+	testHealthServer
+}
+
+func (s *slowHealthServer) RegisterWithGrpcServer(g *grpc.Server) {
+	pb.RegisterHealthServiceServer(g, s)
+}
+
+func (s *slowHealthServer) Liveness(ctx context.Context, _ *pb.LivenessRequest) (*pb.LivenessResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// testOrderServer serves the OpenAPI document of an order service next to the health service.
+type testOrderServer struct {
+	testHealthServer
+}
+
+func (s *testOrderServer) OpenAPIDocs() fs.FS {
+	return fstest.MapFS{"order.swagger.json": {Data: []byte(`{
+  "swagger": "2.0",
+  "info": {"title": "order.proto", "version": "version not set"},
+  "tags": [{"name": "OrderService"}],
+  "consumes": ["application/json"],
+  "produces": ["application/json"],
+  "paths": {
+    "/v1/orders/{id}": {
+      "get": {
+        "operationId": "OrderService_GetOrder",
+        "parameters": [{"name": "id", "in": "path", "required": true, "type": "string"}],
+        "responses": {"200": {"description": "A successful response.", "schema": {"$ref": "#/definitions/pbOrder"}}}
+      }
+    }
+  },
+  "definitions": {
+    "pbOrder": {"type": "object", "properties": {"id": {"type": "string"}}},
+    "rpcStatus": {"type": "object"}
+  }
+}`)}}
+}
+
+// newTestBackend serves the given config's gRPC server over an in-memory listener.
+func newTestBackend(t *testing.T, c *Config) (*grpc.Server, *grpc.ClientConn) {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	s := newGrpcServer(c.Grpc, c.ServiceServers).server
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial("bufnet", append([]grpc.DialOption{grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	}, c.Gateway.dialOptions()...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return s, conn
+}
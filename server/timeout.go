@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRequestTimeoutHeader is the header a client asks for a shorter timeout with, e.g. "X-Request-Timeout: 1.5s".
+const DefaultRequestTimeoutHeader = "X-Request-Timeout"
+
+// RouteTimeout is the timeout of gateway requests to a path, every path under it when it ends with "/".
+type RouteTimeout struct {
+	// Method restricts the route to an HTTP method, any method when empty.
+	Method  string
+	Path    string
+	Timeout time.Duration
+}
+
+// TimeoutConfig configures the deadline of gateway requests, which is propagated to the gRPC handlers.
+type TimeoutConfig struct {
+	// Default applies to requests matching no route, no deadline when zero.
+	Default time.Duration
+	// Routes override Default, the longest matching path wins.
+	Routes []RouteTimeout
+	// Header is read for the timeout asked by the client, DefaultRequestTimeoutHeader when empty.
+	// It is a duration like "500ms" or a number of seconds and can only shorten a configured timeout.
+	Header string
+}
+
+// TimeoutMiddleware sets the deadline of gateway requests from the configured and the requested timeouts.
+// Handlers still running when it passes get DeadlineExceeded, answered with 504 Gateway Timeout.
+func TimeoutMiddleware(cfg TimeoutConfig) HTTPServerMiddleware {
+	header := cfg.Header
+	if header == "" {
+		header = DefaultRequestTimeoutHeader
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := cfg.routeTimeout(r)
+			if requested, ok := parseRequestTimeout(r.Header.Get(header)); ok && (timeout == 0 || requested < timeout) {
+				timeout = requested
+			}
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func (c TimeoutConfig) routeTimeout(r *http.Request) time.Duration {
+	timeout, longest := c.Default, -1
+	for _, rt := range c.Routes {
+		if rt.Method != "" && !strings.EqualFold(rt.Method, r.Method) {
+			continue
+		}
+		p := rt.Path
+		if r.URL.Path != p && !(strings.HasSuffix(p, "/") && strings.HasPrefix(r.URL.Path, p)) {
+			continue
+		}
+		if len(p) > longest {
+			timeout, longest = rt.Timeout, len(p)
+		}
+	}
+	return timeout
+}
+
+func parseRequestTimeout(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d, true
+	}
+	if s, err := strconv.ParseFloat(value, 64); err == nil && s > 0 {
+		return time.Duration(s * float64(time.Second)), true
+	}
+	return 0, false
+}
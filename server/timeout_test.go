@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	c := createConfig([]Option{
+		WithServiceServer(&slowHealthServer{}),
+		WithGatewayTimeout(TimeoutConfig{
+			Default: time.Minute,
+			Routes:  []RouteTimeout{{Method: http.MethodGet, Path: "/version", Timeout: time.Second}},
+		}),
+	})
+	s, conn := newTestBackend(t, c)
+	gw, err := newGatewayServer(c.Gateway, s, conn, c.ServiceServers)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/health", nil)
+	r.Header.Set(DefaultRequestTimeoutHeader, "50ms")
+	w := httptest.NewRecorder()
+	start := time.Now()
+	gw.server.Handler.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestTimeoutConfig_routeTimeout(t *testing.T) {
+	cfg := TimeoutConfig{
+		Default: time.Minute,
+		Routes: []RouteTimeout{
+			{Path: "/v1/", Timeout: 10 * time.Second},
+			{Method: http.MethodPost, Path: "/v1/orders/", Timeout: 30 * time.Second},
+		},
+	}
+	timeout := func(method, path string) time.Duration {
+		return cfg.routeTimeout(httptest.NewRequest(method, path, nil))
+	}
+	assert.Equal(t, time.Minute, timeout(http.MethodGet, "/v2/orders"))
+	assert.Equal(t, 10*time.Second, timeout(http.MethodGet, "/v1/orders/1"))
+	assert.Equal(t, 30*time.Second, timeout(http.MethodPost, "/v1/orders/1"))
+
+	d, ok := parseRequestTimeout("1.5")
+	assert.True(t, ok)
+	assert.Equal(t, 1500*time.Millisecond, d)
+	_, ok = parseRequestTimeout("-1s")
+	assert.False(t, ok)
+}
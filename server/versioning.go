@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// gatewayVersion is a set of ServiceServers served as an API version.
+type gatewayVersion struct {
+	name    string
+	servers []ServiceServer
+}
+
+// appendServiceServers appends the servers which are not in list yet, so a server served
+// under several versions is registered with gRPC and closed once.
+func appendServiceServers(list []ServiceServer, servers ...ServiceServer) []ServiceServer {
+next:
+	for _, sv := range servers {
+		if reflect.TypeOf(sv).Comparable() {
+			for _, o := range list {
+				if o == sv {
+					continue next
+				}
+			}
+		}
+		list = append(list, sv)
+	}
+	return list
+}
+
+// versionRouter dispatches gateway requests to the mux of the requested API version.
+type versionRouter struct {
+	header   string
+	muxes    map[string]http.Handler
+	fallback http.Handler
+}
+
+func (v *versionRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if v.header != "" {
+		if h, ok := v.lookup(r.Header.Get(v.header)); ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	segment := strings.TrimPrefix(r.URL.Path, "/")
+	if i := strings.IndexByte(segment, '/'); i >= 0 {
+		segment = segment[:i]
+	}
+	if h, ok := v.muxes[segment]; ok {
+		h.ServeHTTP(w, r)
+		return
+	}
+	v.fallback.ServeHTTP(w, r)
+}
+
+// lookup accepts header values with or without the "v" of the version name, e.g. "2" for "v2".
+func (v *versionRouter) lookup(version string) (http.Handler, bool) {
+	if version == "" {
+		return nil, false
+	}
+	if h, ok := v.muxes[version]; ok {
+		return h, true
+	}
+	h, ok := v.muxes["v"+version]
+	return h, ok
+}
+
+// stripPathPrefix serves requests under prefix without it, other requests are served as they are.
+func stripPathPrefix(prefix string, next http.Handler) http.Handler {
+	prefix = "/" + strings.Trim(prefix, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		if p != prefix && !strings.HasPrefix(p, prefix+"/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = strings.TrimPrefix(p, prefix)
+		if r2.URL.Path == "" {
+			r2.URL.Path = "/"
+		}
+		if r.URL.RawPath != "" {
+			r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+		}
+		next.ServeHTTP(w, r2)
+	})
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func named(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, name+" "+r.URL.Path)
+	})
+}
+
+func TestVersionRouter(t *testing.T) {
+	h := stripPathPrefix("/orders/", &versionRouter{
+		header:   "X-API-Version",
+		muxes:    map[string]http.Handler{"v2": named("v2")},
+		fallback: named("default"),
+	})
+
+	serve := func(path, version string) string {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if version != "" {
+			r.Header.Set("X-API-Version", version)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "v2 /v2/orders", serve("/orders/v2/orders", ""))
+	assert.Equal(t, "default /v1/orders", serve("/orders/v1/orders", ""))
+	assert.Equal(t, "v2 /orders", serve("/orders/orders", "2"))
+	assert.Equal(t, "default /orders", serve("/orders/orders", "3"))
+	assert.Equal(t, "default /metrics", serve("/metrics", ""))
+	assert.Equal(t, "default /", serve("/orders", ""))
+}
+
+func TestWithGatewayVersion_sharedServer(t *testing.T) {
+	health := &testHealthServer{}
+	c := createConfig([]Option{
+		WithGatewayVersion("v1", health),
+		WithGatewayVersion("v2", health),
+		WithGatewayVersionHeader("X-API-Version"),
+	})
+	assert.Len(t, c.ServiceServers, 1)
+
+	s, conn := newTestBackend(t, c)
+	gw, err := newGatewayServer(c.Gateway, s, conn, c.ServiceServers)
+	require.NoError(t, err)
+
+	for _, version := range []string{"v1", "v2"} {
+		r := httptest.NewRequest(http.MethodGet, "/health", nil)
+		r.Header.Set("X-API-Version", version)
+		w := httptest.NewRecorder()
+		gw.server.Handler.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code, version)
+	}
+}
@@ -0,0 +1,340 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/tikivn/tikit-go-kit/l"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultWebhookMaxBodySize = 1 << 20
+
+// WebhookSecretProvider returns the shared secret a request is signed with,
+// e.g. looked up by a partner code sent along.
+type WebhookSecretProvider func(r *http.Request, body []byte) ([]byte, error)
+
+// WebhookCanonicalizer returns the message a request signature is computed over.
+// timestamp is the checked timestamp of the request when Tolerance is set, empty otherwise,
+// and must be part of the message so that it cannot be changed on a replayed request.
+type WebhookCanonicalizer func(r *http.Request, body []byte, timestamp string) ([]byte, error)
+
+// WebhookSignatureEncoding is how a signature is written in the request.
+type WebhookSignatureEncoding int
+
+const (
+	// WebhookHex is hex encoding, either case.
+	WebhookHex WebhookSignatureEncoding = iota
+	// WebhookBase64 is standard base64 encoding.
+	WebhookBase64
+)
+
+// WebhookSignatureConfig configures the verification of signed partner callbacks on a path.
+type WebhookSignatureConfig struct {
+	// Path of the callback, every path under it when it ends with "/".
+	Path string
+	// Algorithm is the hash used by HMAC, sha256.New when nil.
+	Algorithm func() hash.Hash
+	// Secret provides the shared secret, required.
+	Secret WebhookSecretProvider
+	// Header holds the signature, e.g. "X-Signature".
+	Header string
+	// BodyField holds the signature when Header is empty, in a form or JSON body.
+	BodyField string
+	// Prefix is stripped from the signature, e.g. "sha256=".
+	Prefix string
+	// Encoding of the signature, hex by default.
+	Encoding WebhookSignatureEncoding
+	// Canonicalize builds the signed message, RawBodyCanonicalizer when nil.
+	Canonicalize WebhookCanonicalizer
+	// TimestampHeader or TimestampField holds the time the request was signed at,
+	// in unix seconds, unix milliseconds or RFC 3339.
+	TimestampHeader string
+	TimestampField  string
+	// Tolerance rejects requests signed longer ago or later than it, replays are not checked when zero.
+	// It requires TimestampHeader or TimestampField.
+	Tolerance time.Duration
+	// MaxBodySize limits the buffered body, 1MB by default.
+	MaxBodySize int64
+}
+
+// RawBodyCanonicalizer signs the request body as is, or "<timestamp>.<body>" when the timestamp is checked.
+func RawBodyCanonicalizer(_ *http.Request, body []byte, timestamp string) ([]byte, error) {
+	if timestamp == "" {
+		return body, nil
+	}
+	return append([]byte(timestamp+"."), body...), nil
+}
+
+// OrderedFieldsCanonicalizer signs "k1=v1&k2=v2" built from the given fields of a form or JSON body,
+// in that order, e.g. the MoMo "accessKey=...&amount=...&extraData=..." message.
+// Without fields every field but the excluded ones is used, in alphabetical order.
+// When the timestamp is checked, it must be the value of one of the signed fields.
+func OrderedFieldsCanonicalizer(fields []string, exclude ...string) WebhookCanonicalizer {
+	return func(r *http.Request, body []byte, timestamp string) ([]byte, error) {
+		values, err := webhookBodyFields(r, body)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := fields
+		if len(keys) == 0 {
+			for k := range values {
+				if !containsString(exclude, k) {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+		}
+
+		signed := timestamp == ""
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = k + "=" + values[k]
+			signed = signed || strings.TrimSpace(values[k]) == timestamp
+		}
+		if !signed {
+			return nil, errWebhookTimestampNotSigned
+		}
+		return []byte(strings.Join(pairs, "&")), nil
+	}
+}
+
+// WebhookSignatureMiddleware verifies the HMAC signature of requests to the configured paths
+// before they reach the gateway and rejects invalid ones as Unauthenticated, and bodies larger
+// than MaxBodySize with 413, through the error handler of mux.
+// The body is buffered, so that marshalers downstream can read it again.
+// It panics on an invalid config, e.g. a Tolerance without TimestampHeader nor TimestampField.
+func WebhookSignatureMiddleware(mux *runtime.ServeMux, configs ...WebhookSignatureConfig) HTTPServerMiddleware {
+	for i := range configs {
+		if err := configs[i].validate(); err != nil {
+			panic(fmt.Sprintf("server: webhook %s: %v", configs[i].Path, err))
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c, ok := matchWebhook(configs, r.URL.Path)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := c.verify(r); err != nil {
+				ll.Info("Webhook signature rejected", l.String("path", r.URL.Path), l.Error(err))
+				var rerr error = status.Error(codes.Unauthenticated, "invalid signature")
+				if errors.Is(err, errWebhookBodyTooLarge) {
+					rerr = &runtime.HTTPStatusError{
+						HTTPStatus: http.StatusRequestEntityTooLarge,
+						Err:        status.Error(codes.InvalidArgument, errWebhookBodyTooLarge.Error()),
+					}
+				}
+				_, outbound := runtime.MarshalerForRequest(mux, r)
+				runtime.HTTPError(r.Context(), mux, outbound, w, r, rerr)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func matchWebhook(configs []WebhookSignatureConfig, path string) (*WebhookSignatureConfig, bool) {
+	for i := range configs {
+		p := configs[i].Path
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return &configs[i], true
+		}
+	}
+	return nil, false
+}
+
+var (
+	errWebhookSignature          = errors.New("signature mismatch")
+	errWebhookBodyTooLarge       = errors.New("request body too large")
+	errWebhookTimestampNotSigned = errors.New("timestamp is not signed")
+)
+
+func (c *WebhookSignatureConfig) validate() error {
+	switch {
+	case c.Secret == nil:
+		return errors.New("no secret provider")
+	case c.Header == "" && c.BodyField == "":
+		return errors.New("no signature Header nor BodyField")
+	case c.Tolerance > 0 && c.TimestampHeader == "" && c.TimestampField == "":
+		return errors.New("tolerance without TimestampHeader nor TimestampField")
+	}
+	return nil
+}
+
+func (c *WebhookSignatureConfig) verify(r *http.Request) error {
+	maxSize := c.MaxBodySize
+	if maxSize <= 0 {
+		maxSize = defaultWebhookMaxBodySize
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > maxSize {
+		return errWebhookBodyTooLarge
+	}
+	_ = r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	secret, err := c.Secret(r, body)
+	if err != nil {
+		return err
+	}
+
+	signature, err := c.signature(r, body)
+	if err != nil {
+		return err
+	}
+
+	var timestamp string
+	if c.Tolerance > 0 {
+		if timestamp, err = c.checkTimestamp(r, body); err != nil {
+			return err
+		}
+	}
+
+	canonicalize := c.Canonicalize
+	if canonicalize == nil {
+		canonicalize = RawBodyCanonicalizer
+	}
+	message, err := canonicalize(r, body, timestamp)
+	if err != nil {
+		return err
+	}
+
+	algorithm := c.Algorithm
+	if algorithm == nil {
+		algorithm = sha256.New
+	}
+	mac := hmac.New(algorithm, secret)
+	mac.Write(message)
+	if !hmac.Equal(mac.Sum(nil), signature) {
+		return errWebhookSignature
+	}
+	return nil
+}
+
+func (c *WebhookSignatureConfig) signature(r *http.Request, body []byte) ([]byte, error) {
+	var value string
+	if c.Header != "" {
+		value = r.Header.Get(c.Header)
+	} else {
+		fields, err := webhookBodyFields(r, body)
+		if err != nil {
+			return nil, err
+		}
+		value = fields[c.BodyField]
+	}
+	value = strings.TrimPrefix(strings.TrimSpace(value), c.Prefix)
+	if value == "" {
+		return nil, errors.New("missing signature")
+	}
+
+	if c.Encoding == WebhookBase64 {
+		return base64.StdEncoding.DecodeString(value)
+	}
+	return hex.DecodeString(value)
+}
+
+// checkTimestamp returns the timestamp of the request once checked against the tolerance.
+func (c *WebhookSignatureConfig) checkTimestamp(r *http.Request, body []byte) (string, error) {
+	var value string
+	if c.TimestampHeader != "" {
+		value = r.Header.Get(c.TimestampHeader)
+	} else {
+		fields, err := webhookBodyFields(r, body)
+		if err != nil {
+			return "", err
+		}
+		value = fields[c.TimestampField]
+	}
+
+	ts, err := parseWebhookTimestamp(value)
+	if err != nil {
+		return "", err
+	}
+	if d := time.Since(ts); math.Abs(float64(d)) > float64(c.Tolerance) {
+		return "", fmt.Errorf("timestamp %s out of tolerance", value)
+	}
+	return strings.TrimSpace(value), nil
+}
+
+func parseWebhookTimestamp(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, errors.New("missing timestamp")
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		// milliseconds have 13 digits until the year 2286
+		if n > 1e12 {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// webhookBodyFields returns the top level fields of a form or JSON body as strings.
+func webhookBodyFields(r *http.Request, body []byte) (map[string]string, error) {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	fields := map[string]string{}
+
+	if mt == "application/x-www-form-urlencoded" {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		for k := range values {
+			fields[k] = values.Get(k)
+		}
+		return fields, nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil, fmt.Errorf("invalid webhook body: %w", err)
+	}
+	for k, raw := range obj {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			fields[k] = s
+			continue
+		}
+		// numbers, booleans and objects keep their JSON text
+		fields[k] = string(raw)
+	}
+	return fields, nil
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func sign(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookSignatureMiddleware(t *testing.T) {
+	secret := func(*http.Request, []byte) ([]byte, error) { return []byte("s3cret"), nil }
+	mux := runtime.NewServeMux(runtime.WithErrorHandler(DefaultHTTPErrorHandler))
+	h := WebhookSignatureMiddleware(mux,
+		WebhookSignatureConfig{
+			Path:            "/hooks/raw",
+			Secret:          secret,
+			Header:          "X-Signature",
+			Prefix:          "sha256=",
+			TimestampHeader: "X-Timestamp",
+			Tolerance:       5 * time.Minute,
+		},
+		WebhookSignatureConfig{
+			Path:         "/hooks/momo/",
+			Secret:       secret,
+			BodyField:    "signature",
+			Canonicalize: OrderedFieldsCanonicalizer([]string{"amount", "orderId", "resultCode"}),
+			MaxBodySize:  128,
+		},
+		WebhookSignatureConfig{
+			Path:           "/hooks/zalo",
+			Secret:         secret,
+			Header:         "X-Signature",
+			TimestampField: "ts",
+			Tolerance:      5 * time.Minute,
+			Canonicalize:   OrderedFieldsCanonicalizer([]string{"orderId"}),
+		},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+
+	call := func(path, contentType, body string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		for k, vs := range header {
+			r.Header[k] = vs
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	body := `{"id":"1"}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	w := call("/hooks/raw", "application/json", body, http.Header{
+		"X-Signature": {"sha256=" + sign("s3cret", now+"."+body)},
+		"X-Timestamp": {now},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, body, w.Body.String(), "body is readable downstream")
+
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	w = call("/hooks/raw", "application/json", body, http.Header{
+		"X-Signature": {"sha256=" + sign("s3cret", old+"."+body)},
+		"X-Timestamp": {old},
+	})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// a replayed request with a fresh timestamp does not match the signature
+	w = call("/hooks/raw", "application/json", body, http.Header{
+		"X-Signature": {"sha256=" + sign("s3cret", body)},
+		"X-Timestamp": {now},
+	})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// the canonical message must cover the timestamp
+	w = call("/hooks/zalo", "application/json", `{"orderId":"O1","ts":"`+now+`"}`, http.Header{
+		"X-Signature": {sign("s3cret", "orderId=O1")},
+	})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// containing the timestamp is not enough, it must be a signed field
+	w = call("/hooks/zalo", "application/json", `{"orderId":"O1-`+now+`","ts":"`+now+`"}`, http.Header{
+		"X-Signature": {sign("s3cret", "orderId=O1-"+now)},
+	})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	form := "orderId=O1&amount=300000&resultCode=0&signature=" + sign("s3cret", "amount=300000&orderId=O1&resultCode=0")
+	w = call("/hooks/momo/ipn", "application/x-www-form-urlencoded", form, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = call("/hooks/momo/ipn", "application/json", `{"orderId":"O1","amount":300001,"resultCode":0,"signature":"`+sign("s3cret", "amount=300000&orderId=O1&resultCode=0")+`"}`, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid signature")
+
+	w = call("/hooks/momo/ipn", "application/x-www-form-urlencoded", form+"&extraData="+strings.Repeat("a", 128), nil)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = call("/other", "application/json", body, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestWebhookSignatureMiddleware_invalidConfig(t *testing.T) {
+	secret := func(*http.Request, []byte) ([]byte, error) { return []byte("s3cret"), nil }
+	mux := runtime.NewServeMux()
+
+	assert.PanicsWithValue(t, "server: webhook /hooks: tolerance without TimestampHeader nor TimestampField", func() {
+		WebhookSignatureMiddleware(mux, WebhookSignatureConfig{Path: "/hooks", Secret: secret, Header: "X-Signature", Tolerance: time.Minute})
+	})
+	assert.Panics(t, func() {
+		WebhookSignatureMiddleware(mux, WebhookSignatureConfig{Path: "/hooks", Header: "X-Signature"})
+	})
+}